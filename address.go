@@ -0,0 +1,199 @@
+package jmail
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// An AddressError records an address that failed to parse.
+type AddressError struct {
+	Address string
+	Err     error
+}
+
+func (e *AddressError) Error() string {
+	return "jmail: failed parse address " + e.Address + ": " + e.Err.Error()
+}
+
+func (e *AddressError) Unwrap() error {
+	return e.Err
+}
+
+// AddressErrors holds the addresses which a lenient parse skipped.
+type AddressErrors []*AddressError
+
+func (e AddressErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// GetFromLenient parses the From addresses one by one. Unlike GetFrom, a malformed
+// address doesn't discard the others: the parsed addresses are returned along
+// with AddressErrors for the failures.
+func (j *Jmessage) GetFromLenient() ([]*mail.Address, error) {
+	return j.parseListLenient(j.Header.Get("From"))
+}
+
+// GetToLenient is GetFromLenient for the To addresses.
+func (j *Jmessage) GetToLenient() ([]*mail.Address, error) {
+	return j.parseListLenient(j.Header.Get("To"))
+}
+
+func (j *Jmessage) parseListLenient(header string) ([]*mail.Address, error) {
+	list := []*mail.Address{}
+	var errs AddressErrors
+	for _, addr := range splitAddressList(header) {
+		parsed, err := j.parseAddress(addr)
+		if err != nil {
+			errs = append(errs, &AddressError{Address: addr, Err: err})
+			continue
+		}
+		list = append(list, parsed)
+	}
+	if len(errs) > 0 {
+		return list, errs
+	}
+	return list, nil
+}
+
+// AllRecipients returns the To, Cc and Bcc addresses in this order, without the
+// duplicates (compared with the domain case-insensitively). A header that fails
+// to parse doesn't prevent the others: its failure is returned in AddressErrors.
+func (j *Jmessage) AllRecipients() ([]*mail.Address, error) {
+	list := []*mail.Address{}
+	seen := map[string]bool{}
+	var errs AddressErrors
+	for _, key := range []string{"To", "Cc", "Bcc"} {
+		addrs, err := j.getAddressList(key)
+		if err != nil {
+			errs = append(errs, &AddressError{Address: j.Header.Get(key), Err: err})
+			continue
+		}
+		for _, addr := range addrs {
+			k := addressKey(addr.Address)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			list = append(list, addr)
+		}
+	}
+	if len(errs) > 0 {
+		return list, errs
+	}
+	return list, nil
+}
+
+// addressKey returns addr with its domain lower-cased.
+// The local part is case-sensitive by RFC 5321.
+func addressKey(addr string) string {
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return addr
+	}
+	return addr[:at] + strings.ToLower(addr[at:])
+}
+
+// ParseAddressList parses an address header value, such as the one of a From or
+// To header, with the package level AddressParser, the way GetFrom does.
+func ParseAddressList(header string) ([]*mail.Address, error) {
+	return (&Jmessage{}).parseList(header)
+}
+
+// parseList parses an address list. When the list doesn't parse as a whole
+// because of a display name, the addresses are parsed one by one with parseAddress.
+func (j *Jmessage) parseList(header string) ([]*mail.Address, error) {
+	header = decodeRawISO2022JP(header)
+	list, err := j.parser().ParseList(header)
+	if err == nil {
+		return list, nil
+	}
+	list = []*mail.Address{}
+	for _, addr := range splitAddressList(header) {
+		parsed, perr := j.parseAddress(addr)
+		if perr != nil {
+			return nil, err
+		}
+		list = append(list, parsed)
+	}
+	if len(list) == 0 {
+		return nil, err
+	}
+	return list, nil
+}
+
+// parseAddress parses a single address. When only the display name is broken
+// (an unknown charset or a malformed encoded-word), the address is returned with
+// the name decoded as far as possible instead of an error.
+func (j *Jmessage) parseAddress(addr string) (*mail.Address, error) {
+	addr = decodeRawISO2022JP(addr)
+	parsed, err := j.parser().Parse(addr)
+	if err == nil {
+		return parsed, nil
+	}
+	start := strings.LastIndex(addr, "<")
+	end := strings.LastIndex(addr, ">")
+	if start < 0 || end < start {
+		return nil, err
+	}
+	spec, serr := mail.ParseAddress(addr[start : end+1])
+	if serr != nil {
+		return nil, err
+	}
+	// 表示名は解釈できる範囲でデコードし、残りはそのまま返す
+	name := strings.TrimSpace(addr[:start])
+	if len(name) >= 2 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) {
+		name = name[1 : len(name)-1]
+	}
+	spec.Name = decodeHeader(name)
+	return spec, nil
+}
+
+// splitAddressList splits an address list on the commas outside of quoted strings,
+// angle brackets and comments. Group names ("name:" ... ";") are dropped.
+func splitAddressList(header string) []string {
+	var addrs []string
+	var cur strings.Builder
+	inQuote, escaped := false, false
+	angle, comment := 0, 0
+	flush := func() {
+		if addr := strings.TrimSpace(cur.String()); addr != "" {
+			addrs = append(addrs, addr)
+		}
+		cur.Reset()
+	}
+	for _, c := range header {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && (inQuote || comment > 0):
+			escaped = true
+		case c == '"' && comment == 0:
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			comment++
+		case c == ')' && comment > 0:
+			comment--
+		case comment > 0:
+		case c == '<':
+			angle++
+		case c == '>' && angle > 0:
+			angle--
+		case angle > 0:
+		case c == ',' || c == ';':
+			flush()
+			continue
+		case c == ':':
+			// グループ名は捨てる
+			cur.Reset()
+			continue
+		}
+		cur.WriteRune(c)
+	}
+	flush()
+	return addrs
+}
@@ -0,0 +1,141 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetFromLenient(t *testing.T) {
+	eml := "From: Gopher <from@example.com>, broken@, \"Doe, John\" <john@example.com>, =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <gopher@example.jp>, <<bad>>\r\n" +
+		"To: Team: a@example.com, b@example.com;\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, err := msg.GetFrom(); err == nil {
+		t.Errorf("test: GetFrom should fail for a malformed address")
+	}
+
+	from, err := msg.GetFromLenient()
+	chkaddrs := []string{"from@example.com", "john@example.com", "gopher@example.jp"}
+	if len(from) != len(chkaddrs) {
+		t.Fatalf("test: GetFromLenient count error: (%v)", from)
+	}
+	for i, addr := range from {
+		if addr.Address != chkaddrs[i] {
+			t.Errorf("test: GetFromLenient error: (%v)", addr)
+		}
+	}
+	if from[1].Name != "Doe, John" || from[2].Name != "ホリネズミ" {
+		t.Errorf("test: GetFromLenient name error: (%s, %s)", from[1].Name, from[2].Name)
+	}
+	errs, ok := err.(AddressErrors)
+	if !ok || len(errs) != 2 || errs[0].Address != "broken@" {
+		t.Errorf("test: GetFromLenient should report the failures: (%v)", err)
+	}
+
+	to, err := msg.GetToLenient()
+	if err != nil || len(to) != 2 || to[0].Address != "a@example.com" || to[1].Address != "b@example.com" {
+		t.Errorf("test: GetToLenient error: (%v, %v)", to, err)
+	}
+}
+
+func TestGetFromBrokenName(t *testing.T) {
+	tests := []struct {
+		from  string
+		names []string
+	}{
+		{"=?iso-2022-jp?B?GyRCJVslaiVNJTolXxsoQg==?=Gopher <gopher@example.jp>", []string{"=?iso-2022-jp?B?GyRCJVslaiVNJTolXxsoQg==?=Gopher"}},
+		{"=?iso-2022-jp?B?GyRCJVslaiVNJTolXxsoQg==?= G.O. <gopher@example.jp>", []string{"ホリネズミ G.O."}},
+		{"=?iso-2022-jp?B?GyRCJVslaiVNJTolXxsoQg==?=(go) <gopher@example.jp>", []string{"ホリネズミ(go)"}},
+		{"=?x-unknown?B?Z29waGVy?= <gopher@example.jp>, Gopher <from@example.com>", []string{"=?x-unknown?B?Z29waGVy?=", "Gopher"}},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("From: " + tt.from + "\r\nTo: " + tt.from + "\r\n\r\nMessage body\r\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		from, err := msg.GetFrom()
+		if err != nil || len(from) != len(tt.names) {
+			t.Errorf("test: GetFrom error: %s (%v, %v)", tt.from, from, err)
+			continue
+		}
+		for i, addr := range from {
+			if addr.Name != tt.names[i] {
+				t.Errorf("test: GetFrom name error: %s (%q)", tt.from, addr.Name)
+			}
+		}
+		if to, err := msg.GetTo(); err != nil || len(to) != len(tt.names) || to[0].Address != "gopher@example.jp" {
+			t.Errorf("test: GetTo error: %s (%v, %v)", tt.from, to, err)
+		}
+	}
+
+	msg, err := ReadMessage(strings.NewReader("Sender: =?x-unknown?B?Z29waGVy?= <gopher@example.jp>\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if sender, err := msg.GetSender(); err != nil || sender.Address != "gopher@example.jp" {
+		t.Errorf("test: GetSender error: (%v, %v)", sender, err)
+	}
+}
+
+func TestAllRecipients(t *testing.T) {
+	eml := "To: Gopher <gopher@example.com>, other@example.com\r\n" +
+		"Cc: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <gopher@EXAMPLE.com>, Gopher@example.com\r\n" +
+		"Bcc: broken@\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	list, err := msg.AllRecipients()
+	chkaddrs := []string{"gopher@example.com", "other@example.com", "Gopher@example.com"}
+	if len(list) != len(chkaddrs) {
+		t.Fatalf("test: AllRecipients count error: (%v)", list)
+	}
+	for i, addr := range list {
+		if addr.Address != chkaddrs[i] {
+			t.Errorf("test: AllRecipients error: (%v)", addr)
+		}
+	}
+	if errs, ok := err.(AddressErrors); !ok || len(errs) != 1 || errs[0].Address != "broken@" {
+		t.Errorf("test: AllRecipients should report the Bcc failure: (%v)", err)
+	}
+}
+
+func TestUTF8Addresses(t *testing.T) {
+	eml := "From: 山田 <山田@例え.jp>\r\n" +
+		"To: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <ホリネズミ@example.jp>, \"山田 太郎\" <yamada@例え.jp>, üser@exämple.com\r\n" +
+		"\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	from, err := msg.GetFrom()
+	if err != nil || len(from) != 1 || from[0].Name != "山田" || from[0].Address != "山田@例え.jp" {
+		t.Errorf("test: GetFrom UTF-8 address error: (%v, %v)", from, err)
+	}
+	chkaddrs := []struct{ name, address string }{
+		{"ホリネズミ", "ホリネズミ@example.jp"},
+		{"山田 太郎", "yamada@例え.jp"},
+		{"", "üser@exämple.com"},
+	}
+	to, err := msg.GetTo()
+	if err != nil || len(to) != len(chkaddrs) {
+		t.Fatalf("test: GetTo UTF-8 address error: (%v, %v)", to, err)
+	}
+	for i, addr := range to {
+		if addr.Name != chkaddrs[i].name || addr.Address != chkaddrs[i].address {
+			t.Errorf("test: GetTo UTF-8 address error: (%q, %q)", addr.Name, addr.Address)
+		}
+	}
+}
+
+func TestParseAddressList(t *testing.T) {
+	list, err := ParseAddressList("=?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <gopher@example.jp>, =?x-unknown?B?Z29waGVy?= <other@example.com>")
+	if err != nil || len(list) != 2 || list[0].Name != "ホリネズミ" || list[1].Address != "other@example.com" {
+		t.Errorf("test: ParseAddressList error: (%v, %v)", list, err)
+	}
+	if _, err := ParseAddressList("broken@"); err == nil {
+		t.Errorf("test: ParseAddressList should fail: broken@")
+	}
+}
@@ -0,0 +1,286 @@
+package jmail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DISPOSITION_ATTACHMENT = "attachment"
+)
+
+// An Attachment represents a file attached to a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Data        []byte
+	// Encoding is the lower-cased Content-Transfer-Encoding of the part as sent,
+	// and Raw is the part body before decoding it, to store it byte-identically.
+	Encoding string
+	Raw      []byte
+	// Size, CreationDate and ModificationDate are the size, creation-date and
+	// modification-date parameters of Content-Disposition (RFC 2183), or zero
+	// when absent or unparseable.
+	Size             int64
+	CreationDate     time.Time
+	ModificationDate time.Time
+}
+
+// Attachments returns the attachments of the message with their transfer encoding decoded.
+// Parts with Content-Disposition: attachment, or with a filename or name parameter, are treated as attachments.
+// An attachment that fails to decode is returned with the data decoded so far, along with the first error.
+func (msg Jmessage) Attachments() ([]Attachment, error) {
+	return getAttachments(msg.Header, msg.body(), 0)
+}
+
+func getAttachments(header mail.Header, body io.Reader, depth int) ([]Attachment, error) {
+	var atts []Attachment
+	var attErr error
+	err := walkParts(header, body, depth, "", func(part *Part) error {
+		if !isAttachment(part) {
+			return nil
+		}
+		// 壊れた添付ファイルもデコードできた分は返す
+		att, err := newAttachment(part)
+		atts = append(atts, att)
+		if err != nil && attErr == nil {
+			attErr = errors.Wrapf(err, "getAttachments: %s:", att.Filename)
+		}
+		return nil
+	})
+	if err == nil {
+		err = attErr
+	}
+	return atts, err
+}
+
+// isAttachment reports whether a part has Content-Disposition: attachment, or a filename.
+func isAttachment(part *Part) bool {
+	disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	return disposition == DISPOSITION_ATTACHMENT || partFilename(part.Header) != ""
+}
+
+// AttachmentMeta describes an attachment streamed by AttachmentReaders.
+type AttachmentMeta struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Encoding    string
+}
+
+// AttachmentReaders calls fn for each attachment of the message, in order, with
+// a reader streaming its data with the transfer encoding decoded, so that large
+// attachments are never held in memory. The reader is only valid until fn
+// returns. An error returned by fn stops the walk and is returned.
+// The attachments are the ones of Attachments.
+func (msg Jmessage) AttachmentReaders(fn func(meta AttachmentMeta, r io.Reader) error) error {
+	return walkParts(msg.Header, msg.streamBody(), 0, "", func(part *Part) error {
+		if !isAttachment(part) {
+			return nil
+		}
+		meta := newAttachmentMeta(part)
+		return fn(meta, transferDecoder(meta.Encoding, part.body))
+	})
+}
+
+func newAttachmentMeta(part *Part) AttachmentMeta {
+	return AttachmentMeta{
+		Filename:    partFilename(part.Header),
+		ContentType: part.MediaType,
+		ContentID:   strings.Trim(part.Header.Get("Content-ID"), "<> "),
+		Encoding:    strings.ToLower(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding"))),
+	}
+}
+
+// InlineParts returns the parts with a Content-ID, such as the images of
+// multipart/related, keyed by the Content-ID without the angle brackets.
+// It is meant for resolving the cid: URLs of an HTML body.
+func (msg Jmessage) InlineParts() (map[string]Attachment, error) {
+	parts := map[string]Attachment{}
+	err := walkParts(msg.Header, msg.body(), 0, "", func(part *Part) error {
+		if strings.Trim(part.Header.Get("Content-ID"), "<> ") == "" {
+			return nil
+		}
+		att, err := newAttachment(part)
+		parts[att.ContentID] = att
+		if err != nil {
+			return errors.Wrapf(err, "InlineParts: %s:", part.Header.Get("Content-ID"))
+		}
+		return nil
+	})
+	return parts, err
+}
+
+// newAttachment reads part into an Attachment.
+// On a decode error, Data holds what was decoded before the error.
+func newAttachment(part *Part) (Attachment, error) {
+	meta := newAttachmentMeta(part)
+	att := Attachment{
+		Filename:    meta.Filename,
+		ContentType: meta.ContentType,
+		ContentID:   meta.ContentID,
+		Encoding:    meta.Encoding,
+	}
+	params := rawParams(part.Header.Get("Content-Disposition"))
+	if size, err := strconv.ParseInt(strings.TrimSpace(params["size"]), 10, 64); err == nil && size >= 0 {
+		att.Size = size
+	}
+	att.CreationDate = parseDispositionDate(params["creation-date"])
+	att.ModificationDate = parseDispositionDate(params["modification-date"])
+	// 生のデータは MaxPartSize (と MaxMessageSize) で制限済み; MaxBodySize はデコード後のサイズ
+	raw, err := io.ReadAll(part.body)
+	att.Raw = raw
+	if err != nil {
+		return att, err
+	}
+	att.Data, err = readAllLimited(transferDecoder(att.Encoding, bytes.NewReader(raw)), MaxBodySize)
+	return att, err
+}
+
+// parseDispositionDate parses a date parameter of Content-Disposition, which is
+// an RFC 5322 date-time. Invalid dates give the zero time.
+func parseDispositionDate(value string) time.Time {
+	date, err := mail.ParseDate(strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
+
+// partFilename returns the decoded filename of a part, or the name parameter
+// of its Content-Type when Content-Disposition has no filename.
+func partFilename(header mail.Header) string {
+	filename := decodeFilename(rawParams(header.Get("Content-Disposition")))
+	if filename == "" {
+		// filename がなければ Content-Type の name を使う
+		filename = decodeFilename(rawParams(header.Get("Content-Type")))
+	}
+	return filename
+}
+
+// decodeFilename returns the UTF-8 filename from the filename or name parameter.
+// params must be the raw parameters returned by rawParams, so that RFC 2231
+// continuations (filename*0*, filename*1*, ...) are reassembled and decoded
+// with the declared charset. RFC 2047 encoded-words are decoded as well.
+func decodeFilename(params map[string]string) string {
+	for _, key := range []string{"filename", "name"} {
+		if filename := decodeParam(params, key); filename != "" {
+			return filename
+		}
+	}
+	return ""
+}
+
+func decodeParam(params map[string]string, key string) string {
+	if v, ok := params[key+"*"]; ok {
+		// filename*=charset'lang'value
+		charset, value := split2231(v)
+		return decode2231(charset, []byte(percentDecode(value)))
+	}
+
+	var buf bytes.Buffer
+	var charset string
+	for n := 0; ; n++ {
+		k := key + "*" + strconv.Itoa(n)
+		if v, ok := params[k+"*"]; ok {
+			if n == 0 {
+				charset, v = split2231(v)
+			}
+			buf.WriteString(percentDecode(v))
+		} else if v, ok := params[k]; ok {
+			buf.WriteString(v)
+		} else {
+			break
+		}
+	}
+	if buf.Len() > 0 {
+		return decode2231(charset, buf.Bytes())
+	}
+
+	value := params[key]
+	if decoded, err := wordDecoder.DecodeHeader(value); err == nil {
+		value = decoded
+	}
+	return value
+}
+
+// split2231 splits an RFC 2231 extended value into its charset and the value.
+func split2231(v string) (charset, value string) {
+	parts := strings.SplitN(v, "'", 3)
+	if len(parts) != 3 {
+		return "", v
+	}
+	return parts[0], parts[2]
+}
+
+func decode2231(charset string, value []byte) string {
+	if charset == "" {
+		return string(value)
+	}
+	decoded, err := DecodeCharset(charset, value)
+	if err != nil {
+		// 未知の charset はそのまま
+		return string(value)
+	}
+	return string(decoded)
+}
+
+func percentDecode(v string) string {
+	decoded, err := url.PathUnescape(v)
+	if err != nil {
+		return v
+	}
+	return decoded
+}
+
+// rawParams returns the parameters of a Content-Type or Content-Disposition value
+// without RFC 2231 processing. Keys are lowercased, values are unquoted.
+func rawParams(v string) map[string]string {
+	params := make(map[string]string)
+	i := strings.IndexByte(v, ';')
+	if i < 0 {
+		return params
+	}
+	v = v[i+1:]
+	for {
+		v = strings.TrimLeft(v, " \t\r\n;")
+		eq := strings.IndexByte(v, '=')
+		if eq < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(v[:eq]))
+		v = strings.TrimLeft(v[eq+1:], " \t\r\n")
+		var value bytes.Buffer
+		if strings.HasPrefix(v, `"`) {
+			// quoted-string
+			j := 1
+			for ; j < len(v) && v[j] != '"'; j++ {
+				if v[j] == '\\' && j+1 < len(v) {
+					j++
+				}
+				value.WriteByte(v[j])
+			}
+			if j < len(v) {
+				j++
+			}
+			v = v[j:]
+		} else {
+			end := strings.IndexByte(v, ';')
+			if end < 0 {
+				end = len(v)
+			}
+			value.WriteString(strings.TrimSpace(v[:end]))
+			v = v[end:]
+		}
+		params[key] = value.String()
+	}
+}
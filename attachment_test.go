@@ -0,0 +1,237 @@
+package jmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAttachments(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	tests := []struct {
+		eml   string
+		names []string
+		cids  []string
+	}{
+		{"./testbody/05test-multipart.eml", []string{"doc.png", "talks.png"}, []string{"", ""}},
+		{"./testbody/06test-html.eml", []string{"talks.png", "doc.png"}, []string{"14fe53dca31997701021", "14fe53dead16ce2c4732"}},
+	}
+	for _, tt := range tests {
+		f, err := os.Open(tt.eml)
+		if err != nil {
+			t.Fatalf("test: Failed open file: %s (%v)", tt.eml, err)
+		}
+		defer f.Close()
+		msg, err := ReadMessage(f)
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %s (%v)", tt.eml, err)
+		}
+		atts, err := msg.Attachments()
+		if err != nil {
+			t.Errorf("test: Attachments error: %s (%v)", tt.eml, err)
+			continue
+		}
+		if len(atts) != len(tt.names) {
+			t.Errorf("test: Attachments count error: %s (%d)", tt.eml, len(atts))
+			continue
+		}
+		for i, a := range atts {
+			if a.Filename != tt.names[i] || a.ContentID != tt.cids[i] || a.ContentType != "image/png" {
+				t.Errorf("test: Attachment error: %s (%s, %s, %s)", tt.eml, a.Filename, a.ContentID, a.ContentType)
+			}
+			if !bytes.HasPrefix(a.Data, png) {
+				t.Errorf("test: Attachment data error: %s (%s)", tt.eml, a.Filename)
+			}
+		}
+	}
+}
+
+func TestAttachmentReaders(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/06test-html.eml")
+	atts, err := msg.Attachments()
+	if err != nil {
+		t.Fatalf("test: Attachments error: %v", err)
+	}
+	i := 0
+	err = msg.AttachmentReaders(func(meta AttachmentMeta, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if i >= len(atts) || meta.Filename != atts[i].Filename || meta.ContentID != atts[i].ContentID ||
+			meta.ContentType != atts[i].ContentType || !bytes.Equal(data, atts[i].Data) {
+			t.Errorf("test: AttachmentReaders error: %d (%s, %s, %s)", i, meta.Filename, meta.ContentID, meta.ContentType)
+		}
+		i++
+		return nil
+	})
+	if err != nil || i != len(atts) {
+		t.Errorf("test: AttachmentReaders count error: (%d, %v)", i, err)
+	}
+
+	stop := errors.New("stop")
+	i = 0
+	err = msg.AttachmentReaders(func(meta AttachmentMeta, r io.Reader) error {
+		i++
+		return stop
+	})
+	if err != stop || i != 1 {
+		t.Errorf("test: AttachmentReaders stop error: (%d, %v)", i, err)
+	}
+}
+
+func TestInlineParts(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/06test-html.eml")
+	parts, err := msg.InlineParts()
+	if err != nil {
+		t.Fatalf("test: InlineParts error: %v", err)
+	}
+	chknames := map[string]string{"14fe53dca31997701021": "talks.png", "14fe53dead16ce2c4732": "doc.png"}
+	if len(parts) != len(chknames) {
+		t.Fatalf("test: InlineParts count error: (%d)", len(parts))
+	}
+	for cid, name := range chknames {
+		p, ok := parts[cid]
+		if !ok || p.Filename != name || !bytes.HasPrefix(p.Data, []byte("\x89PNG\r\n\x1a\n")) {
+			t.Errorf("test: InlineParts error: %s (%s)", cid, p.Filename)
+		}
+	}
+
+	msg = openTestMessage(t, "./testbody/05test-multipart.eml")
+	if parts, err := msg.InlineParts(); err != nil || len(parts) != 0 {
+		t.Errorf("test: InlineParts without Content-ID error: (%d, %v)", len(parts), err)
+	}
+}
+
+func TestAttachmentsBroken(t *testing.T) {
+	data := bytes.Repeat([]byte("gopher"), 20)
+	enc := base64.StdEncoding.EncodeToString(data)
+	eml := "Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+		"--BOUNDARY\r\nContent-Type: application/octet-stream\r\nContent-Disposition: attachment; filename=broken.bin\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		enc[:40] + "#" + enc[40:] + "\r\n" +
+		"--BOUNDARY\r\nContent-Type: application/octet-stream\r\nContent-Disposition: attachment; filename=ok.bin\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		enc + "\r\n--BOUNDARY--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	atts, err := msg.Attachments()
+	if err == nil || !strings.Contains(err.Error(), "broken.bin") {
+		t.Errorf("test: Attachments should report the broken attachment: (%v)", err)
+	}
+	if len(atts) != 2 || !bytes.Equal(atts[0].Data, data) || !bytes.Equal(atts[1].Data, data) {
+		t.Errorf("test: Attachments error: (%d)", len(atts))
+	}
+}
+
+func TestAttachmentsRaw(t *testing.T) {
+	raw, err := os.ReadFile("./testbody/05test-multipart.eml")
+	if err != nil {
+		t.Fatalf("test: Failed read file: (%v)", err)
+	}
+	msg := openTestMessage(t, "./testbody/05test-multipart.eml")
+	atts, err := msg.Attachments()
+	if err != nil || len(atts) == 0 {
+		t.Fatalf("test: Attachments error: (%v)", err)
+	}
+	for _, a := range atts {
+		if a.Encoding != ENC_BASE64 || !bytes.Contains(raw, a.Raw) {
+			t.Errorf("test: Attachment raw error: %s (%s)", a.Filename, a.Encoding)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(a.Raw)), ""))
+		if err != nil || !bytes.Equal(decoded, a.Data) {
+			t.Errorf("test: Attachment raw decode error: %s (%v)", a.Filename, err)
+		}
+	}
+}
+
+func TestAttachmentsDisposition(t *testing.T) {
+	eml := "Content-Type: multipart/mixed; boundary=X\r\n\r\n" +
+		"--X\r\nContent-Type: text/plain\r\n\r\nbody\r\n" +
+		"--X\r\nContent-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=a.bin; size=4;\r\n" +
+		"\tcreation-date=\"Wed, 16 Sep 2015 05:32:04 +0900\"; modification-date=\"broken\"\r\n\r\ndata\r\n" +
+		"--X\r\nContent-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=b.bin; size=-1\r\n\r\ndata\r\n--X--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	atts, err := msg.Attachments()
+	if err != nil || len(atts) != 2 {
+		t.Fatalf("test: Attachments error: (%d, %v)", len(atts), err)
+	}
+	want := time.Date(2015, 9, 16, 5, 32, 4, 0, time.FixedZone("", 9*60*60))
+	if a := atts[0]; a.Size != 4 || !a.CreationDate.Equal(want) || !a.ModificationDate.IsZero() {
+		t.Errorf("test: Attachment disposition error: %s (%d, %s, %s)", a.Filename, a.Size, a.CreationDate, a.ModificationDate)
+	}
+	if a := atts[1]; a.Size != 0 || !a.CreationDate.IsZero() {
+		t.Errorf("test: Attachment disposition error: %s (%d, %s)", a.Filename, a.Size, a.CreationDate)
+	}
+}
+
+func TestAttachmentsFilename(t *testing.T) {
+	eml := `From: Gopher <from@example.com>
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: text/plain; charset="utf-8"
+
+go go gopher!
+--BOUNDARY
+Content-Type: text/plain; name="=?ISO-2022-JP?B?GyRCO3FOQRsoQi50eHQ=?="
+Content-Disposition: attachment
+Content-Transfer-Encoding: base64
+
+aGVsbG8=
+--BOUNDARY
+Content-Type: application/pdf
+Content-Disposition: attachment; filename*=utf-8''%E5%A0%B1%E5%91%8A%E6%9B%B8.pdf
+Content-Transfer-Encoding: quoted-printable
+
+hello=
+--BOUNDARY--
+`
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	atts, err := msg.Attachments()
+	if err != nil {
+		t.Fatalf("test: Attachments error: %v", err)
+	}
+	chknames := []string{"資料.txt", "報告書.pdf"}
+	if len(atts) != len(chknames) {
+		t.Fatalf("test: Attachments count error: (%d)", len(atts))
+	}
+	for i, a := range atts {
+		if a.Filename != chknames[i] {
+			t.Errorf("test: Attachment filename error: (%s)", a.Filename)
+		}
+		if string(a.Data) != "hello" {
+			t.Errorf("test: Attachment data error: %s (%q)", a.Filename, a.Data)
+		}
+	}
+}
+
+func TestDecodeFilename(t *testing.T) {
+	chkname := "報告書_2015年度.txt"
+	tests := []string{
+		`attachment; filename*=iso-2022-jp''%1B%24BJs9p%3Dq%1B%28B_2015%1B%24BG%2FEY%1B%28B.txt`,
+		`attachment; filename*=shift_jis'ja'%95%F1%8D%90%8F%91_2015%94N%93x.txt`,
+		"attachment;\r\n filename*0*=euc-jp''%CA%F3%B9%F0%BD%F1;\r\n filename*1*=_2015%C7%AF%C5%D9;\r\n filename*2=\".txt\"",
+		`attachment; filename*0*=utf-8''%E5%A0%B1%E5%91%8A%E6%9B%B8; filename*1*=_2015%E5%B9%B4%E5%BA%A6.txt`,
+		`attachment; filename="=?UTF-8?B?5aCx5ZGK5pu4XzIwMTXlubTluqYudHh0?="`,
+	}
+	for _, tt := range tests {
+		if filename := decodeFilename(rawParams(tt)); filename != chkname {
+			t.Errorf("test: decodeFilename error: %s (%s)", tt, filename)
+		}
+	}
+}
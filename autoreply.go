@@ -0,0 +1,63 @@
+package jmail
+
+import (
+	"strings"
+)
+
+// IsAutoReply reports whether the message is an automatic response, like a
+// vacation reply, that should not be answered automatically again. It checks
+// Auto-Submitted (RFC 3834), Precedence: auto_reply and the vendor headers
+// X-Autoreply, X-Autorespond, X-Mail-Autoreply (any value but "no" or "false"),
+// X-Autogenerated: Reply, and X-Autoreply-From, whose mere presence is enough
+// since it holds the address of the responder.
+func (j *Jmessage) IsAutoReply() bool {
+	// RFC 3834: "no" 以外 (auto-generated, auto-replied, auto-notified) は自動送信
+	for _, v := range j.Header["Auto-Submitted"] {
+		if token := headerToken(v); token != "" && token != "no" {
+			return true
+		}
+	}
+	for _, key := range []string{"X-Autoreply", "X-Autorespond", "X-Mail-Autoreply"} {
+		for _, v := range j.Header[key] {
+			if token := headerToken(v); token != "" && token != "no" && token != "false" {
+				return true
+			}
+		}
+	}
+	// X-Autoreply-From の値はアドレスなので、あるかどうかだけを見る
+	if _, ok := j.Header["X-Autoreply-From"]; ok {
+		return true
+	}
+	for _, v := range j.Header["X-Autogenerated"] {
+		if headerToken(v) == "reply" {
+			return true
+		}
+	}
+	for _, v := range j.Header["Precedence"] {
+		if token := headerToken(v); token == "auto_reply" || token == "auto-reply" {
+			return true
+		}
+	}
+	return false
+}
+
+// headerToken returns the lower-cased first token of a header value, without
+// the parameters after ";" and the comments in parentheses.
+func headerToken(value string) string {
+	var token strings.Builder
+	depth := 0
+	for _, r := range unfold(value) {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth > 0:
+		case r == ';':
+			return strings.ToLower(strings.TrimSpace(token.String()))
+		default:
+			token.WriteRune(r)
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(token.String()))
+}
@@ -0,0 +1,41 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsAutoReply(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"Auto-Submitted: no\r\n", false},
+		{"Auto-Submitted: No (sent by a human)\r\n", false},
+		{"Auto-Submitted: auto-replied\r\n", true},
+		{"Auto-Submitted: Auto-Generated; owner-email=\"admin@example.com\"\r\n", true},
+		{"Auto-Submitted: (vacation)\r\n auto-notified\r\n", true},
+		{"X-Autoreply: yes\r\n", true},
+		{"X-Autoreply: no\r\n", false},
+		{"X-Autorespond: Out of office\r\n", true},
+		{"X-Mail-Autoreply: yes\r\n", true},
+		{"X-Mail-Autoreply: false\r\n", false},
+		{"X-Autoreply-From: no-reply@example.com\r\n", true},
+		{"X-Autoreply-From: No (vacation) <no@example.com>\r\n", true},
+		{"X-Autogenerated: Reply\r\n", true},
+		{"X-Autogenerated: Forward\r\n", false},
+		{"Precedence: auto_reply\r\n", true},
+		{"Precedence: bulk\r\n", false},
+		{"Precedence: list\r\n", false},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Subject: test\r\n" + tt.header + "\r\nMessage body\r\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if got := msg.IsAutoReply(); got != tt.want {
+			t.Errorf("test: IsAutoReply error: %q (%v)", tt.header, got)
+		}
+	}
+}
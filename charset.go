@@ -0,0 +1,262 @@
+package jmail
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// DecodeCharset converts data labeled with charset into UTF-8.
+// iso-2022-jp, euc-jp, shift_jis, windows-31j and utf-8 (with their common aliases) are supported,
+// as well as the other charsets known to golang.org/x/text by their IANA names and aliases.
+// iso-2022-jp-2 and iso-2022-jp-3 are decoded best-effort: their characters outside of
+// JIS X 0208, JIS X 0212 and JIS X 0201 become "〓".
+// Shift_JIS is decoded as CP932, so NEC and IBM extension characters are kept.
+func DecodeCharset(charset string, data []byte) ([]byte, error) {
+	dec, err := charsetDecoder(charset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DecodeCharset:")
+	}
+	decoded, _, err := transform.Bytes(dec, data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DecodeCharset:")
+	}
+	return decoded, nil
+}
+
+// newCharsetReader returns a reader converting input from charset into UTF-8.
+// It has the signature of mime.WordDecoder.CharsetReader.
+func newCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	dec, err := charsetDecoder(charset)
+	if err != nil {
+		return nil, err
+	}
+	return transform.NewReader(input, dec), nil
+}
+
+// wordCharsetReader is the CharsetReader of the encoded-words. Their text is
+// short, so it is converted at once, sparing the buffers of transform.Reader.
+func wordCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	dec, err := charsetDecoder(charset)
+	if err != nil {
+		return nil, err
+	}
+	if dec == transform.Nop {
+		return input, nil
+	}
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	decoded, _, err := transform.Bytes(dec, data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decoded), nil
+}
+
+// 利用者が登録した charset
+var (
+	charsetsMu sync.RWMutex
+	charsets   = map[string]func() transform.Transformer{}
+)
+
+// RegisterCharset registers the decoder to UTF-8 of a charset for bodies, headers
+// and addresses. name is matched case-insensitively, and a registered charset takes
+// precedence over the built-in iso-2022-jp, euc-jp, shift_jis, windows-31j and utf-8.
+// RegisterCharset is safe to call concurrently with itself and with decoding,
+// but is usually called from an init function.
+func RegisterCharset(name string, newDecoder func() transform.Transformer) {
+	charsetsMu.Lock()
+	defer charsetsMu.Unlock()
+	charsets[strings.ToLower(name)] = newDecoder
+}
+
+func charsetDecoder(charset string) (transform.Transformer, error) {
+	charsetsMu.RLock()
+	newDecoder, ok := charsets[strings.ToLower(charset)]
+	charsetsMu.RUnlock()
+	if ok {
+		return newDecoder(), nil
+	}
+	switch {
+	case strings.ToLower(charset) == CHARSET_ISO2022JP:
+		return japanese.ISO2022JP.NewDecoder(), nil
+	case isISO2022JPExt(charset):
+		return transform.Chain(&iso2022jpExt{}, japanese.ISO2022JP.NewDecoder()), nil
+	case isEUCJP(charset):
+		return japanese.EUCJP.NewDecoder(), nil
+	case isShiftJIS(charset), isWindows31J(charset):
+		// x/text の ShiftJIS は CP932 (NEC/IBM 拡張文字) も含む
+		return japanese.ShiftJIS.NewDecoder(), nil
+	case isUTF8(charset):
+		return transform.Nop, nil
+	}
+	// その他の charset は IANA の登録名と別名から引く
+	for _, index := range []*ianaindex.Index{ianaindex.MIME, ianaindex.IANA} {
+		if enc, err := index.Encoding(strings.TrimSpace(charset)); err == nil && enc != nil {
+			return enc.NewDecoder(), nil
+		}
+	}
+	return nil, &UnknownCharsetError{Charset: charset}
+}
+
+// An UnknownCharsetError is returned by DecodeCharset, and by the decoding of
+// encoded-words, for a charset with no decoder. Bodies in such a charset are
+// returned undecoded, with the error recorded in Warnings.
+type UnknownCharsetError struct {
+	Charset string
+}
+
+func (e *UnknownCharsetError) Error() string {
+	return "jmail: unknown charset: " + e.Charset
+}
+
+// isISO2022JPExt reports whether charset is one of the extended ISO-2022-JP labels.
+func isISO2022JPExt(charset string) bool {
+	switch strings.ToLower(charset) {
+	case "iso-2022-jp-2", "iso-2022-jp-3", "iso-2022-jp-2004", "iso-2022-jp-1":
+		return true
+	}
+	return false
+}
+
+// isShiftJIS reports whether charset is one of the Shift_JIS labels.
+func isShiftJIS(charset string) bool {
+	switch strings.ToLower(charset) {
+	case CHARSET_SHIFTJIS, "shift-jis", "sjis", "x-sjis":
+		return true
+	}
+	return false
+}
+
+// isWindows31J reports whether charset is one of the Windows-31J (CP932) labels.
+func isWindows31J(charset string) bool {
+	switch strings.ToLower(charset) {
+	case CHARSET_WINDOWS31J, "cp932", "ms932", "ms_kanji", "x-ms-cp932":
+		return true
+	}
+	return false
+}
+
+// isEUCJP reports whether charset is one of the EUC-JP labels.
+func isEUCJP(charset string) bool {
+	switch strings.ToLower(charset) {
+	case CHARSET_EUCJP, "eucjp":
+		return true
+	}
+	return false
+}
+
+// isUTF8 reports whether charset is UTF-8 or its ASCII subset.
+func isUTF8(charset string) bool {
+	switch strings.ToLower(charset) {
+	case CHARSET_UTF8, "utf8", "us-ascii", "ascii":
+		return true
+	}
+	return false
+}
+
+// GuessCharset guesses the charset of Japanese text without a charset label.
+// It is a heuristic: ISO-2022-JP is detected by its escape sequences, valid UTF-8
+// (including plain ASCII) gives utf-8, and other 8-bit text is Shift_JIS or EUC-JP
+// depending on which of the two reads with fewer invalid byte sequences and
+// half-width katakana, which are rare in real text.
+func GuessCharset(body []byte) string {
+	for _, esc := range []string{"\x1b$B", "\x1b$@", "\x1b(J", "\x1b(I"} {
+		if bytes.Contains(body, []byte(esc)) {
+			return CHARSET_ISO2022JP
+		}
+	}
+	if utf8.Valid(trimPartialRune(body)) {
+		return CHARSET_UTF8
+	}
+	if scoreEUCJP(body) < scoreShiftJIS(body) {
+		return CHARSET_EUCJP
+	}
+	return CHARSET_SHIFTJIS
+}
+
+// trimPartialRune removes an incomplete UTF-8 sequence at the end of data,
+// which a head cut out of a longer text may have.
+func trimPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// scoreShiftJIS rates how unlikely data is Shift_JIS: each invalid byte counts 2
+// and each half-width katakana 1.
+func scoreShiftJIS(data []byte) int {
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c < 0x80:
+		case 0xa1 <= c && c <= 0xdf:
+			// 半角カナ
+			n++
+		case (0x81 <= c && c <= 0x9f) || (0xe0 <= c && c <= 0xfc):
+			if i+1 < len(data) && 0x40 <= data[i+1] && data[i+1] <= 0xfc && data[i+1] != 0x7f {
+				i++
+			} else if i+1 < len(data) {
+				n += 2
+			}
+		default:
+			n += 2
+		}
+	}
+	return n
+}
+
+// scoreEUCJP rates how unlikely data is EUC-JP in the same way as scoreShiftJIS.
+func scoreEUCJP(data []byte) int {
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c < 0x80:
+		case c == 0x8e:
+			// 半角カナ
+			if i+1 < len(data) && 0xa1 <= data[i+1] && data[i+1] <= 0xdf {
+				i++
+				n++
+			} else if i+1 < len(data) {
+				n += 2
+			}
+		case c == 0x8f:
+			// 補助漢字
+			if i+2 < len(data) && isEUCByte(data[i+1]) && isEUCByte(data[i+2]) {
+				i += 2
+			} else if i+2 < len(data) {
+				n += 2
+			}
+		case isEUCByte(c):
+			if i+1 < len(data) && isEUCByte(data[i+1]) {
+				i++
+			} else if i+1 < len(data) {
+				n += 2
+			}
+		default:
+			n += 2
+		}
+	}
+	return n
+}
+
+func isEUCByte(c byte) bool {
+	return 0xa1 <= c && c <= 0xfe
+}
@@ -0,0 +1,156 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+func TestDecodeCharset(t *testing.T) {
+	want := "ホリネズミ"
+	tests := []struct {
+		charset string
+		data    []byte
+	}{
+		{"ISO-2022-JP", []byte("\x1b$B%[%j%M%:%_\x1b(B")},
+		{"euc-jp", []byte("\xa5\xdb\xa5\xea\xa5\xcd\xa5\xba\xa5\xdf")},
+		{"Shift_JIS", []byte("\x83\x7a\x83\x8a\x83\x6c\x83\x59\x83\x7e")},
+		{"x-sjis", []byte("\x83\x7a\x83\x8a\x83\x6c\x83\x59\x83\x7e")},
+		{"UTF-8", []byte(want)},
+	}
+	for _, tt := range tests {
+		got, err := DecodeCharset(tt.charset, tt.data)
+		if err != nil {
+			t.Errorf("test: DecodeCharset error: %s (%v)", tt.charset, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("test: DecodeCharset: %s (%s)", tt.charset, got)
+		}
+	}
+
+	if _, err := DecodeCharset("x-unknown", []byte("abc")); err == nil {
+		t.Errorf("test: DecodeCharset: unknown charset should be an error")
+	}
+}
+
+func TestDecodeCharsetCP932(t *testing.T) {
+	// NEC 特殊文字, NEC 選定 IBM 拡張文字, IBM 拡張文字
+	want := "①№ⅰ纊"
+	data := []byte("\x87\x40\x87\x82\xfa\x40\xed\x40")
+	for _, charset := range []string{"Windows-31J", "CP932", "MS_Kanji", "Shift_JIS"} {
+		got, err := DecodeCharset(charset, data)
+		if err != nil {
+			t.Errorf("test: DecodeCharset error: %s (%v)", charset, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("test: DecodeCharset: %s (%s)", charset, got)
+		}
+	}
+}
+
+func TestDecodeCharsetIANA(t *testing.T) {
+	tests := []struct {
+		charset string
+		data    []byte
+		want    string
+	}{
+		{"ISO-8859-1", []byte("caf\xe9"), "café"},
+		{"latin1", []byte("caf\xe9"), "café"},
+		{"windows-1252", []byte("\x93quote\x94"), "\u201cquote\u201d"},
+		{"csISO2022JP", []byte("\x1b$B%[%j%M%:%_\x1b(B"), "ホリネズミ"},
+		{"EUC-KR", []byte("\xc7\xd1"), "한"},
+		{"Big5", []byte("\xa4\xa4"), "中"},
+		{"KOI8-R", []byte("\xf0"), "П"},
+	}
+	for _, tt := range tests {
+		got, err := DecodeCharset(tt.charset, tt.data)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("test: DecodeCharset IANA error: %s (%s, %v)", tt.charset, got, err)
+		}
+	}
+
+	if subj := decodeHeader("=?iso-8859-1?q?caf=E9?="); subj != "café" {
+		t.Errorf("test: decodeHeader IANA error: %s", subj)
+	}
+}
+
+func TestGuessCharset(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{[]byte("plain ascii text"), CHARSET_UTF8},
+		{[]byte("サイトを更新した状態に保つことは重要です。"), CHARSET_UTF8},
+		{[]byte("サイト"[:8]), CHARSET_UTF8},
+		{[]byte("\x1b$B%[%j%M%:%_\x1b(B"), CHARSET_ISO2022JP},
+		{[]byte("\xa5\xdb\xa5\xea\xa5\xcd\xa5\xba\xa5\xdf\xa4\xcf\xa4\xa4\xa4\xeb"), CHARSET_EUCJP},
+		{[]byte("\x83\x7a\x83\x8a\x83\x6c\x83\x59\x83\x7e\x82\xcd\x82\xa2\x82\xe9"), CHARSET_SHIFTJIS},
+		{[]byte("\xc6\xfc\xcb\xdc\xb8\xec\x8e\xb1"), CHARSET_EUCJP},
+		{[]byte("\xb1\xb2\xb3 \x88\x9f"), CHARSET_SHIFTJIS},
+	}
+	for _, tt := range tests {
+		if got := GuessCharset(tt.data); got != tt.want {
+			t.Errorf("test: GuessCharset error: %q (%s)", tt.data, got)
+		}
+	}
+}
+
+func TestUnknownCharsetError(t *testing.T) {
+	var unknown *UnknownCharsetError
+	if _, err := DecodeCharset("x-unknown", []byte("abc")); !errors.As(err, &unknown) || unknown.Charset != "x-unknown" {
+		t.Errorf("test: DecodeCharset UnknownCharsetError error: (%v)", err)
+	}
+	if _, err := newCharsetReader("x-bogus", strings.NewReader("abc")); !errors.As(err, &unknown) || unknown.Charset != "x-bogus" {
+		t.Errorf("test: CharsetReader UnknownCharsetError error: (%v)", err)
+	}
+
+	msg, err := ReadMessage(strings.NewReader("Content-Type: text/plain; charset=x-unknown\r\n\r\nabc\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "abc\r\n" {
+		t.Errorf("test: DecBody unknown charset error: (%q, %v)", body, err)
+	}
+	if w := msg.Warnings(); len(w) != 1 || !errors.As(w[0], &unknown) || unknown.Charset != "x-unknown" {
+		t.Errorf("test: DecBody UnknownCharsetError warning error: (%v)", w)
+	}
+}
+
+func TestRegisterCharset(t *testing.T) {
+	defer func() {
+		charsetsMu.Lock()
+		delete(charsets, "x-gopher")
+		charsetsMu.Unlock()
+	}()
+	if _, err := DecodeCharset("x-gopher", []byte("gopher")); err == nil {
+		t.Fatalf("test: DecodeCharset should fail before RegisterCharset")
+	}
+	RegisterCharset("X-Gopher", func() transform.Transformer {
+		return runes.Map(unicode.ToUpper)
+	})
+
+	if got, err := DecodeCharset("x-gopher", []byte("gopher")); err != nil || string(got) != "GOPHER" {
+		t.Errorf("test: DecodeCharset error: (%s, %v)", got, err)
+	}
+	eml := "From: =?x-gopher?Q?gopher?= <from@example.com>\r\nSubject: =?X-GOPHER?B?Z29waGVy?=\r\n" +
+		"Content-Type: text/plain; charset=x-gopher\r\n\r\ngo go gopher!"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if subj := msg.DecSubject(); subj != "GOPHER" {
+		t.Errorf("test: DecSubject error: %s", subj)
+	}
+	if from, err := msg.GetFrom(); err != nil || from[0].Name != "GOPHER" {
+		t.Errorf("test: GetFrom error: (%v, %v)", from, err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "GO GO GOPHER!" {
+		t.Errorf("test: DecBody error: (%s, %v)", body, err)
+	}
+}
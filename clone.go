@@ -0,0 +1,82 @@
+package jmail
+
+import (
+	"bytes"
+	"io"
+	"net/mail"
+)
+
+// Clone returns a copy of the message with its own header map, which can be
+// modified without changing j. The body is read into memory and shared, so
+// both messages can decode it, any number of times.
+func (j *Jmessage) Clone() *Jmessage {
+	if j.buffer == nil {
+		j.buffer = &bodyBuffer{r: j.Body}
+	}
+	j.Message.Body = j.buffer.reader()
+
+	header := make(mail.Header, len(j.Header))
+	for key, values := range j.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	clone := *j
+	clone.Message = &mail.Message{Header: header, Body: j.buffer.reader()}
+	clone.rawHeaders = append([]byte(nil), j.rawHeaders...)
+	clone.warnings = &warningSet{}
+	clone.warnings.add(j.Warnings()...)
+	return &clone
+}
+
+// body returns the reader of the message body: a new reader over the buffered
+// body when there is one, or else Body itself.
+func (msg Jmessage) body() io.Reader {
+	if msg.buffer != nil {
+		return msg.buffer.reader()
+	}
+	return msg.Body
+}
+
+// streamBody is body, but hands over a body not read into memory yet instead of
+// buffering it. The buffer then only returns ErrBodyStreamed.
+func (msg Jmessage) streamBody() io.Reader {
+	if msg.buffer != nil && msg.buffer.r != nil {
+		r := msg.buffer.r
+		msg.buffer.r, msg.buffer.err = nil, ErrBodyStreamed
+		return r
+	}
+	return msg.body()
+}
+
+// bodyBuffer holds a message body read into memory, up to MaxMessageSize bytes.
+// It is not safe for concurrent use until the body has been read.
+type bodyBuffer struct {
+	r    io.Reader
+	data []byte
+	err  error
+}
+
+// reader reads the body on the first call, and returns a new reader over it.
+// A read error, or ErrBodyTooLarge for a body over MaxMessageSize, is returned by
+// the reader after the data read before it.
+func (b *bodyBuffer) reader() io.Reader {
+	if b.r != nil {
+		if MaxMessageSize > 0 {
+			b.data, b.err = readAllLimited(b.r, MaxMessageSize)
+		} else {
+			b.data, b.err = io.ReadAll(b.r)
+		}
+		b.r = nil
+	}
+	if b.err != nil {
+		return io.MultiReader(bytes.NewReader(b.data), &errorReader{b.err})
+	}
+	return bytes.NewReader(b.data)
+}
+
+type errorReader struct {
+	err error
+}
+
+func (e *errorReader) Read(p []byte) (int, error) {
+	return 0, e.err
+}
@@ -0,0 +1,69 @@
+package jmail
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestClone(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/05test-multipart.eml")
+	clone := msg.Clone()
+	clone.Header["Subject"] = []string{"redacted"}
+	clone.Header["From"][0] = "redacted@example.com"
+
+	if got := msg.DecSubject(); got != "go run gopher" {
+		t.Errorf("test: Clone Subject error: %s", got)
+	}
+	if got := msg.Header.Get("From"); got == "redacted@example.com" {
+		t.Errorf("test: Clone From error: %s", got)
+	}
+
+	first, err := msg.DecBody()
+	if err != nil || len(first) == 0 {
+		t.Fatalf("test: DecBody error: (%v)", err)
+	}
+	for i, m := range []*Jmessage{msg, clone, clone} {
+		body, err := m.DecBody()
+		if err != nil || string(body) != string(first) {
+			t.Errorf("test: Clone DecBody error: %d (%s, %v)", i, body, err)
+		}
+	}
+	if got := clone.DecSubject(); got != "redacted" {
+		t.Errorf("test: Clone DecSubject error: %s", got)
+	}
+}
+
+func TestBodyBufferLimit(t *testing.T) {
+	defer func(max int64) { MaxMessageSize = max }(MaxMessageSize)
+	MaxMessageSize = 10
+	msg, err := ReadMessage(strings.NewReader("Subject: test\r\n\r\n" + strings.Repeat("a", 10000)))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, err := msg.DecBody(); errors.Cause(err) != ErrBodyTooLarge {
+		t.Errorf("test: DecBody should fail: (%v)", err)
+	}
+	if len(msg.buffer.data) > 10 {
+		t.Errorf("test: body buffer limit error: (%d)", len(msg.buffer.data))
+	}
+
+	// MaxBodySize はデコード後のサイズなので、base64 の生の本文はそれより大きくてよい
+	MaxMessageSize = 0
+	defer func(max int64) { MaxBodySize = max }(MaxBodySize)
+	MaxBodySize = 1000
+	data := strings.Repeat("a", 900)
+	eml := "Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+		"--BOUNDARY\r\nContent-Type: application/octet-stream; name=\"a.bin\"\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(data)) + "\r\n" +
+		"--BOUNDARY--\r\n"
+	msg, err = ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if atts, err := msg.Attachments(); err != nil || len(atts) != 1 || string(atts[0].Data) != data {
+		t.Errorf("test: Attachments under MaxBodySize error: (%d, %v)", len(atts), err)
+	}
+}
@@ -0,0 +1,35 @@
+package jmail
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ReadMessageContext reads a message from r like ReadMessage, but stops reading
+// when ctx is done. The cancellation also applies to the later reads of the body
+// by DecBody and the other body methods, which then return the wrapped ctx.Err().
+func ReadMessageContext(ctx context.Context, r io.Reader) (*Jmessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrapf(err, "ReadMessageContext:")
+	}
+	msg, err := ReadMessage(&ctxReader{ctx: ctx, r: r})
+	if err != nil && ctx.Err() != nil {
+		return msg, errors.Wrapf(ctx.Err(), "ReadMessageContext:")
+	}
+	return msg, err
+}
+
+// ctxReader is a reader checking ctx before each read.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, errors.Wrapf(err, "jmail: read canceled:")
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,47 @@
+package jmail
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestReadMessageContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msg, err := ReadMessageContext(ctx, strings.NewReader("Subject: test\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessageContext error: %v", err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "Message body\r\n" {
+		t.Errorf("test: DecBody error: %q (%v)", body, err)
+	}
+
+	// ヘッダー読み込み後にキャンセル
+	ctx, cancel = context.WithCancel(context.Background())
+	r := io.MultiReader(strings.NewReader("Subject: test\r\n\r\n"), &cancelReader{cancel: cancel}, strings.NewReader("Message body\r\n"))
+	msg, err = ReadMessageContext(ctx, r)
+	if err != nil {
+		t.Fatalf("test: ReadMessageContext error: %v", err)
+	}
+	if _, err := msg.DecBody(); errors.Cause(err) != context.Canceled {
+		t.Errorf("test: DecBody cancel error: %v", err)
+	}
+
+	if _, err := ReadMessageContext(ctx, strings.NewReader("Subject: test\r\n\r\n")); errors.Cause(err) != context.Canceled {
+		t.Errorf("test: ReadMessageContext cancel error: %v", err)
+	}
+}
+
+// cancelReader calls cancel at the first read and returns no data.
+type cancelReader struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) {
+	c.cancel()
+	return 0, io.EOF
+}
@@ -0,0 +1,57 @@
+package jmail
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 数字の時差に置き換える textual zone
+var dateZones = map[string]string{
+	"JST": "+0900",
+	"UT":  "+0000",
+	"GMT": "+0000",
+	"EST": "-0500",
+	"EDT": "-0400",
+	"CST": "-0600",
+	"CDT": "-0500",
+	"MST": "-0700",
+	"MDT": "-0600",
+	"PST": "-0800",
+	"PDT": "-0700",
+}
+
+// GetDate parses the Date header.
+// Besides RFC 5322 dates, it accepts times without leading zeros and textual zones such as JST.
+func (j *Jmessage) GetDate() (time.Time, error) {
+	value := j.Header.Get("Date")
+	date, err := mail.ParseDate(normalizeDate(value))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "GetDate: %q:", value)
+	}
+	return date, nil
+}
+
+// normalizeDate rewrites the non-standard parts of a date so that mail.ParseDate accepts it.
+func normalizeDate(value string) string {
+	fields := strings.Fields(value)
+	for i, field := range fields {
+		if offset, ok := dateZones[strings.ToUpper(field)]; ok {
+			fields[i] = offset
+			continue
+		}
+		if strings.Contains(field, ":") {
+			// 5:3:6 -> 05:03:06
+			parts := strings.Split(field, ":")
+			for n, p := range parts {
+				if len(p) == 1 {
+					parts[n] = "0" + p
+				}
+			}
+			fields[i] = strings.Join(parts, ":")
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,43 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetDate(t *testing.T) {
+	jst := time.FixedZone("", 9*60*60)
+	tests := []struct {
+		date string
+		want time.Time
+	}{
+		{"Wed, 16 Sep 2015 05:32:06 +0900", time.Date(2015, 9, 16, 5, 32, 6, 0, jst)},
+		{"Wed, 16 Sep 2015 05:32:06 +0900 (JST)", time.Date(2015, 9, 16, 5, 32, 6, 0, jst)},
+		{"Wed, 16 Sep 2015 05:32:06 JST", time.Date(2015, 9, 16, 5, 32, 6, 0, jst)},
+		{"Sun, 6 Sep 2015 5:2:6 jst", time.Date(2015, 9, 6, 5, 2, 6, 0, jst)},
+		{"15 Sep 2015 16:17:23 GMT", time.Date(2015, 9, 16, 1, 17, 23, 0, jst)},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Date: " + tt.date + "\n\nMessage body\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		date, err := msg.GetDate()
+		if err != nil {
+			t.Errorf("test: GetDate error: %s (%v)", tt.date, err)
+			continue
+		}
+		if !date.Equal(tt.want) {
+			t.Errorf("test: GetDate error: %s (%v)", tt.date, date)
+		}
+	}
+
+	msg, err := ReadMessage(strings.NewReader("Date: yesterday\n\nMessage body\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, err := msg.GetDate(); err == nil {
+		t.Errorf("test: GetDate should fail for an invalid date")
+	}
+}
@@ -0,0 +1,58 @@
+package jmail
+
+import (
+	"net/mail"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// An Envelope holds the decoded headers of a message used to index or list it.
+type Envelope struct {
+	From      []*mail.Address
+	To        []*mail.Address
+	Cc        []*mail.Address
+	Subject   string
+	Date      time.Time
+	MessageID string
+
+	// Errors holds the failures of the fields which are left empty.
+	Errors []error
+}
+
+// Envelope returns the decoded From, To, Cc, Subject, Date and Message-ID.
+// A field that fails to parse is left empty and its error appended to
+// Envelope.Errors. Absent headers are empty without an error.
+func (j *Jmessage) Envelope() (*Envelope, error) {
+	if j.Message == nil {
+		return nil, errors.New("Envelope: no message")
+	}
+	env := &Envelope{
+		Subject:   j.DecSubject(),
+		MessageID: j.MessageID(),
+	}
+	addrs := []struct {
+		key  string
+		list *[]*mail.Address
+	}{
+		{"From", &env.From},
+		{"To", &env.To},
+		{"Cc", &env.Cc},
+	}
+	for _, a := range addrs {
+		list, err := j.getAddressList(a.key)
+		if err != nil {
+			env.Errors = append(env.Errors, errors.Wrapf(err, "Envelope: %s:", a.key))
+			list = []*mail.Address{}
+		}
+		*a.list = list
+	}
+	if j.Header.Get("Date") != "" {
+		date, err := j.GetDate()
+		if err != nil {
+			env.Errors = append(env.Errors, errors.Wrapf(err, "Envelope:"))
+		}
+		env.Date = date
+	}
+	return env, nil
+}
@@ -0,0 +1,37 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvelope(t *testing.T) {
+	msg := openTestMessage(t, "./testsubj/01test-iso2022jpb.eml")
+	env, err := msg.Envelope()
+	if err != nil {
+		t.Fatalf("test: Envelope error: %v", err)
+	}
+	if env.Subject != msg.DecSubject() || len(env.From) != 1 || len(env.To) != 1 || len(env.Cc) != 0 {
+		t.Errorf("test: Envelope error: (%+v)", env)
+	}
+	if !env.Date.Equal(time.Date(2015, 9, 15, 16, 17, 23, 0, time.UTC)) || len(env.Errors) != 0 {
+		t.Errorf("test: Envelope date error: (%v, %v)", env.Date, env.Errors)
+	}
+
+	eml := "From: broken@\r\nTo: Gopher <to@example.com>\r\nDate: yesterday\r\nMessage-ID: <abc@example.com>\r\n\r\nMessage body\r\n"
+	msg, err = ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	env, err = msg.Envelope()
+	if err != nil {
+		t.Fatalf("test: Envelope error: %v", err)
+	}
+	if len(env.Errors) != 2 || len(env.From) != 0 || len(env.To) != 1 || !env.Date.IsZero() {
+		t.Errorf("test: Envelope partial error: (%+v)", env)
+	}
+	if env.MessageID != "abc@example.com" || env.Subject != "" {
+		t.Errorf("test: Envelope error: (%q, %q)", env.MessageID, env.Subject)
+	}
+}
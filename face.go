@@ -0,0 +1,38 @@
+package jmail
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoFace is returned by FaceImage when the message has neither a Face nor an X-Face header.
+var ErrNoFace = errors.New("jmail: no Face header")
+
+// ErrUnsupported is returned for data in a format jmail can't decode, such as X-Face images.
+var ErrUnsupported = errors.New("jmail: unsupported format")
+
+// pngSignature は PNG ファイルの先頭 8 バイト
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// FaceImage returns the sender picture of the Face header, a base64 PNG, and its
+// media type "image/png". The compressed bitmap of X-Face is not decoded: a
+// message with only X-Face returns ErrUnsupported.
+func (j *Jmessage) FaceImage() ([]byte, string, error) {
+	face := j.Header.Get("Face")
+	if strings.TrimSpace(face) == "" {
+		if strings.TrimSpace(j.Header.Get("X-Face")) != "" {
+			return nil, "", ErrUnsupported
+		}
+		return nil, "", ErrNoFace
+	}
+	data, err := readAllLimited(newBase64Reader(strings.NewReader(face)), MaxBodySize)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "FaceImage:")
+	}
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, "", errors.New("FaceImage: not a PNG image")
+	}
+	return data, "image/png", nil
+}
@@ -0,0 +1,37 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFaceImage(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x000\x00\x00\x000"
+	tests := []struct {
+		header string
+		want   string
+		err    error
+	}{
+		{"Face: iVBORw0KGgoAAAANSUhE\r\n UgAAADAAAAAw\r\n", png, nil},
+		{"X-Face: \"8S<l~n#>G@8<bTw\r\n", "", ErrUnsupported},
+		{"", "", ErrNoFace},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Subject: test\r\n" + tt.header + "\r\nMessage body\r\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		data, mediatype, err := msg.FaceImage()
+		if err != tt.err || string(data) != tt.want || (tt.err == nil && mediatype != "image/png") {
+			t.Errorf("test: FaceImage error: %q (%s, %v)", tt.header, mediatype, err)
+		}
+	}
+
+	msg, err := ReadMessage(strings.NewReader("Face: Z29waGVy\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, _, err := msg.FaceImage(); err == nil {
+		t.Errorf("test: FaceImage should fail: not a PNG")
+	}
+}
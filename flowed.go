@@ -0,0 +1,100 @@
+package jmail
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/textproto"
+	"strings"
+)
+
+// isFlowed reports whether the header declares text/plain; format=flowed
+// (RFC 3676), and whether delsp=yes.
+func isFlowed(header textproto.MIMEHeader) (flowed, delsp bool) {
+	mediatype, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediatype != MEDIATYPE_TEXT_PLAIN || !strings.EqualFold(params["format"], "flowed") {
+		return false, false
+	}
+	return true, strings.EqualFold(params["delsp"], "yes")
+}
+
+// flowedReader joins the soft-wrapped lines of format=flowed text, the lines
+// ending with a space, into one line per paragraph. Quote marks are kept, and
+// lines of different quote depths are never joined.
+type flowedReader struct {
+	r     *bufio.Reader
+	delsp bool
+	out   []byte // 未読の出力
+	open  bool   // 段落の途中 (直前の行が flowed)
+	depth int    // 途中の段落の引用の深さ
+	eol   string // 途中の段落を閉じる改行
+	err   error
+}
+
+func newFlowedReader(r io.Reader, delsp bool) io.Reader {
+	return &flowedReader{r: bufio.NewReader(r), delsp: delsp}
+}
+
+func (f *flowedReader) Read(p []byte) (int, error) {
+	for len(f.out) == 0 && f.err == nil {
+		line, err := f.r.ReadBytes('\n')
+		if len(line) > 0 {
+			f.line(line)
+		}
+		if err != nil {
+			if f.open {
+				// 最後の行が flowed でも段落を閉じる
+				f.out = append(f.out, f.eol...)
+				f.open = false
+			}
+			f.err = err
+		}
+	}
+	n := copy(p, f.out)
+	f.out = f.out[n:]
+	if len(f.out) > 0 {
+		return n, nil
+	}
+	return n, f.err
+}
+
+// line unwraps one line of flowed text into out.
+func (f *flowedReader) line(line []byte) {
+	eol := ""
+	if bytes.HasSuffix(line, []byte("\r\n")) {
+		eol = "\r\n"
+	} else if bytes.HasSuffix(line, []byte("\n")) {
+		eol = "\n"
+	}
+	line = line[:len(line)-len(eol)]
+
+	depth := 0
+	for depth < len(line) && line[depth] == '>' {
+		depth++
+	}
+	content := line[depth:]
+	// space-stuffing を戻す
+	content = bytes.TrimPrefix(content, []byte(" "))
+
+	if f.open && depth != f.depth {
+		// 引用の深さが変わったら段落を閉じる
+		f.out = append(f.out, f.eol...)
+		f.open = false
+	}
+	if !f.open && depth > 0 {
+		f.out = append(f.out, strings.Repeat(">", depth)+" "...)
+	}
+	// 署名の区切り "-- " は flowed 扱いしない
+	if bytes.HasSuffix(content, []byte(" ")) && string(content) != "-- " && eol != "" {
+		if f.delsp {
+			content = content[:len(content)-1]
+		}
+		f.out = append(f.out, content...)
+		f.open, f.depth, f.eol = true, depth, eol
+		return
+	}
+	f.out = append(f.out, content...)
+	f.out = append(f.out, eol...)
+	f.open = false
+}
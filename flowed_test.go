@@ -0,0 +1,39 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlowed(t *testing.T) {
+	tests := []struct {
+		params string
+		body   string
+		want   string
+	}{
+		{"", "soft \r\nbreak\r\n", "soft \r\nbreak\r\n"},
+		{"; format=flowed", "soft \r\nwrapped \r\nline\r\nhard\r\n", "soft wrapped line\r\nhard\r\n"},
+		{"; format=flowed; delsp=yes", "ホリネズミは、 \r\n哺乳類の総称である。\r\n", "ホリネズミは、哺乳類の総称である。\r\n"},
+		{"; format=Flowed", " >not quoted \r\nline\r\n", ">not quoted line\r\n"},
+		{"; format=flowed",
+			"I agree.\r\n\r\n> Shall we \r\n> meet \r\n>> next \r\n>> week?\r\n> Sure.\r\n-- \r\nGopher\r\n",
+			"I agree.\r\n\r\n> Shall we meet \r\n>> next week?\r\n> Sure.\r\n-- \r\nGopher\r\n"},
+		{"; format=flowed", "last \r\nline ", "last line "},
+		{"; format=flowed", "first \r\nlast \r\n", "first last \r\n"},
+		{"; format=flowed; delsp=yes", "first \nlast \n", "firstlast\n"},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Content-Type: text/plain; charset=utf-8" + tt.params + "\r\n\r\n" + tt.body))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBody()
+		if err != nil || string(body) != tt.want {
+			t.Errorf("test: format=flowed error: %q (%q, %v)", tt.body, body, err)
+		}
+	}
+
+	if flowed, delsp := isFlowed(map[string][]string{"Content-Type": {"text/html; format=flowed"}}); flowed || delsp {
+		t.Errorf("test: isFlowed text/html error")
+	}
+}
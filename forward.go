@@ -0,0 +1,38 @@
+package jmail
+
+import (
+	"io"
+	"net/mail"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MEDIATYPE_RFC822 = "message/rfc822"
+)
+
+// ForwardedMessages parses the message/rfc822 parts (forwarded or attached messages)
+// into messages of their own. Messages nested in them are not included.
+func (msg Jmessage) ForwardedMessages() ([]*Jmessage, error) {
+	return getForwarded(msg.Header, msg.body(), 0)
+}
+
+func getForwarded(header mail.Header, body io.Reader, depth int) ([]*Jmessage, error) {
+	var msgs []*Jmessage
+	err := walkParts(header, body, depth, "", func(part *Part) error {
+		if part.MediaType != MEDIATYPE_RFC822 {
+			return nil
+		}
+		data, err := part.Bytes()
+		if err != nil {
+			return errors.Wrapf(err, "getForwarded:")
+		}
+		fwd, err := ParseMessage(data)
+		if err != nil {
+			return errors.Wrapf(err, "getForwarded: ParseMessage:")
+		}
+		msgs = append(msgs, fwd)
+		return nil
+	})
+	return msgs, err
+}
@@ -0,0 +1,49 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForwardedMessages(t *testing.T) {
+	eml := `From: Support <support@example.com>
+Subject: Fwd: complaint
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: text/plain; charset="utf-8"
+
+see below
+--BOUNDARY
+Content-Type: message/rfc822
+
+From: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <gopher@example.jp>
+Subject: =?UTF-8?B?44OG44K544OI?=
+Content-Type: text/plain; charset="utf-8"
+
+original body
+--BOUNDARY--
+`
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	fwds, err := msg.ForwardedMessages()
+	if err != nil {
+		t.Fatalf("test: ForwardedMessages error: %v", err)
+	}
+	if len(fwds) != 1 {
+		t.Fatalf("test: ForwardedMessages count error: (%d)", len(fwds))
+	}
+	fwd := fwds[0]
+	if fwd.DecSubject() != "テスト" {
+		t.Errorf("test: forwarded subject error: (%s)", fwd.DecSubject())
+	}
+	from, err := fwd.GetFrom()
+	if err != nil || len(from) != 1 || from[0].Name != "ホリネズミ" {
+		t.Errorf("test: forwarded from error: (%v, %v)", from, err)
+	}
+	if body, err := fwd.DecBody(); err != nil || string(body) != "original body" {
+		t.Errorf("test: forwarded body error: (%s, %v)", body, err)
+	}
+}
@@ -0,0 +1,96 @@
+package jmail
+
+import (
+	"golang.org/x/text/transform"
+)
+
+// iso2022jpExt rewrites the escape sequences of ISO-2022-JP-2 (RFC 1554) and
+// ISO-2022-JP-3/2004 into plain ISO-2022-JP, which the x/text decoder handles.
+// It is best-effort: JIS X 0213 plane 1 is read as JIS X 0208, which it extends,
+// and the characters of the other sets (JIS X 0213 plane 2, GB 2312, KS C 5601
+// and the single-shifted ISO-8859-1/7) become the geta mark "〓".
+type iso2022jpExt struct {
+	cur         string // 最後に書き出した G0 のエスケープシーケンス
+	unsupported bool   // 未対応の 2 バイト文字集合の中
+}
+
+const (
+	escASCII = "\x1b(B"
+	escJIS   = "\x1b$B"
+	geta     = "\x22\x2e" // JIS X 0208 の 〓
+)
+
+func (t *iso2022jpExt) Reset() {
+	*t = iso2022jpExt{}
+}
+
+func (t *iso2022jpExt) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		c := src[nSrc]
+		var out string
+		n := 1
+		switch {
+		case c == 0x1b:
+			seq, ok := escapeSequence(src[nSrc:])
+			if !ok {
+				if !atEOF {
+					return nDst, nSrc, transform.ErrShortSrc
+				}
+				seq = string(src[nSrc:])
+			}
+			n = len(seq)
+			switch seq {
+			case "\x1b$(O", "\x1b$(Q":
+				// JIS X 0213 第 1 面は JIS X 0208 として読む
+				out, t.cur, t.unsupported = escJIS, escJIS, false
+			case "\x1b$A", "\x1b$(C", "\x1b$(P":
+				out, t.cur, t.unsupported = escJIS, escJIS, true
+			case "\x1b.A", "\x1b.F":
+				// G2 の指示は単一シフトで使う
+			default:
+				if len(seq) == 3 && seq[1] == 'N' {
+					// 単一シフトされた G2 の文字
+					back := t.cur
+					if back == "" {
+						back = escASCII
+					}
+					out = escJIS + geta + back
+				} else {
+					out, t.cur, t.unsupported = seq, seq, false
+				}
+			}
+		case t.unsupported && c > 0x20:
+			if nSrc+1 >= len(src) && !atEOF {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			out, n = geta, 2
+			if nSrc+1 >= len(src) {
+				n = 1
+			}
+		case t.unsupported:
+			// 制御文字 (改行など) で ASCII に戻す
+			out, t.cur, t.unsupported = escASCII+string(c), escASCII, false
+		default:
+			out = string(c)
+		}
+		if nDst+len(out) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], out)
+		nSrc += n
+	}
+	return nDst, nSrc, nil
+}
+
+// escapeSequence returns the escape sequence at the head of b.
+// ok is false when b ends in the middle of it.
+func escapeSequence(b []byte) (seq string, ok bool) {
+	n := 3
+	if len(b) >= 3 && b[1] == '$' && (b[2] == '(' || b[2] == ')') {
+		n = 4
+	}
+	if len(b) < n {
+		return "", false
+	}
+	return string(b[:n]), true
+}
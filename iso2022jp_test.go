@@ -0,0 +1,43 @@
+package jmail
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeISO2022JPExt(t *testing.T) {
+	tests := []struct {
+		charset string
+		data    string
+		want    string
+	}{
+		{"iso-2022-jp-2", "\x1b$B%[%j%M%:%_\x1b(B", "ホリネズミ"},
+		{"iso-2022-jp-2", "a\x1b.A\x1bNib", "a〓b"},
+		{"iso-2022-jp-2", "\x1b$B%[\x1b.A\x1bNi%j\x1b(B", "ホ〓リ"},
+		{"iso-2022-jp-2", "\x1b$A0!0\"\r\nabc", "〓〓\r\nabc"},
+		{"iso-2022-jp-2", "\x1b$(C0!\x1b(Babc", "〓abc"},
+		{"iso-2022-jp-2", "\x1b$(D0!\x1b(B", "丂"},
+		{"iso-2022-jp-3", "\x1b$(Q%[%j%M%:%_\x1b(B", "ホリネズミ"},
+		{"iso-2022-jp-2004", "\x1b$(O%[\x1b$(P!!\x1b(B", "ホ〓"},
+	}
+	for _, tt := range tests {
+		got, err := DecodeCharset(tt.charset, []byte(tt.data))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("test: DecodeCharset error: %s %q (%q, %v)", tt.charset, tt.data, got, err)
+		}
+	}
+}
+
+func TestDecodeISO2022JPExtReader(t *testing.T) {
+	data := "\x1b$(Q%[%j\x1b(B Caf\x1b.A\x1bNi \x1b$A0!\x1b(B end"
+	r, err := newCharsetReader("ISO-2022-JP-2", iotest.OneByteReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("test: newCharsetReader error: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "ホリ Caf〓 〓 end" {
+		t.Errorf("test: ISO-2022-JP-2 reader error: (%q, %v)", got, err)
+	}
+}
@@ -0,0 +1,67 @@
+package jmail
+
+import (
+	"encoding/json"
+	"io"
+	"net/mail"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// jsonMessage is the JSON form of a Jmessage.
+type jsonMessage struct {
+	Subject     string        `json:"subject"`
+	From        []jsonAddress `json:"from"`
+	To          []jsonAddress `json:"to"`
+	Cc          []jsonAddress `json:"cc"`
+	Date        *time.Time    `json:"date,omitempty"`
+	MessageID   string        `json:"message_id,omitempty"`
+	ContentType string        `json:"content_type"`
+	// []byte は base64 で出力される
+	Body []byte `json:"body"`
+}
+
+type jsonAddress struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+}
+
+// MarshalJSON encodes the decoded subject, addresses, date, content type and
+// body (as base64) of the message. It reads the body, like DecBody. The body
+// is empty when the message has no text part.
+// Headers that fail to parse are left empty.
+func (msg Jmessage) MarshalJSON() ([]byte, error) {
+	env, err := msg.Envelope()
+	if err != nil {
+		return nil, errors.Wrapf(err, "MarshalJSON:")
+	}
+	mediatype, _, _ := msg.ContentType()
+	jm := jsonMessage{
+		Subject:     env.Subject,
+		From:        jsonAddresses(env.From),
+		To:          jsonAddresses(env.To),
+		Cc:          jsonAddresses(env.Cc),
+		MessageID:   env.MessageID,
+		ContentType: mediatype,
+	}
+	if !env.Date.IsZero() {
+		jm.Date = &env.Date
+	}
+	jm.Body, err = msg.DecBody()
+	if err == io.EOF {
+		// テキストのパートがなければ空の本文にする
+		jm.Body = []byte{}
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "MarshalJSON:")
+	}
+	return json.Marshal(jm)
+}
+
+func jsonAddresses(list []*mail.Address) []jsonAddress {
+	addrs := make([]jsonAddress, len(list))
+	for i, addr := range list {
+		addrs[i] = jsonAddress{Name: addr.Name, Address: addr.Address}
+	}
+	return addrs
+}
@@ -0,0 +1,54 @@
+package jmail
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/01test-iso2022jp.eml")
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("test: MarshalJSON error: %v", err)
+	}
+	var got struct {
+		Subject     string
+		From        []struct{ Name, Address string }
+		To          []struct{ Name, Address string }
+		Cc          []struct{ Name, Address string }
+		Date        string
+		ContentType string `json:"content_type"`
+		Body        []byte
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("test: Unmarshal error: %v (%s)", err, data)
+	}
+	if got.Subject != msg.DecSubject() || len(got.From) != 1 || got.From[0].Address != "from@example.com" || len(got.Cc) != 0 {
+		t.Errorf("test: MarshalJSON error: %s", data)
+	}
+	if got.Date == "" || got.ContentType != MEDIATYPE_TEXT_PLAIN {
+		t.Errorf("test: MarshalJSON error: %s", data)
+	}
+	if string(got.Body) != "サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n" {
+		t.Errorf("test: MarshalJSON body error: %s", got.Body)
+	}
+}
+
+func TestMarshalJSONNoText(t *testing.T) {
+	eml := "From: Gopher <from@example.com>\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+		"--BOUNDARY\r\nContent-Type: image/png; name=\"doc.png\"\r\nContent-Transfer-Encoding: base64\r\n\r\niVBORw0KGgo=\r\n" +
+		"--BOUNDARY--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("test: MarshalJSON without text error: %v", err)
+	}
+	if !strings.Contains(string(data), `"body":""`) {
+		t.Errorf("test: MarshalJSON without text body error: %s", data)
+	}
+}
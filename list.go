@@ -0,0 +1,59 @@
+package jmail
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ListUnsubscribe returns the mailto: and http(s): URLs of the List-Unsubscribe
+// header (RFC 2369) in order. Malformed entries are skipped; an error is returned
+// only when the header is present but holds no usable URL.
+// An absent header gives nil and nil error.
+func (j *Jmessage) ListUnsubscribe() ([]*url.URL, error) {
+	value := unfold(j.Header.Get("List-Unsubscribe"))
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var urls []*url.URL
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if !strings.HasPrefix(entry, "<") || !strings.HasSuffix(entry, ">") {
+			continue
+		}
+		// URL 中の空白は折り返しの名残なので取り除く
+		u, err := url.Parse(strings.Join(strings.Fields(entry[1:len(entry)-1]), ""))
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "mailto":
+			if u.Opaque == "" {
+				continue
+			}
+		case "http", "https":
+			if u.Host == "" {
+				continue
+			}
+		default:
+			continue
+		}
+		urls = append(urls, u)
+	}
+	if len(urls) == 0 {
+		return nil, errors.Errorf("ListUnsubscribe: no valid URL: %q", value)
+	}
+	return urls, nil
+}
+
+// ListUnsubscribeOneClick reports whether the message supports the one-click
+// unsubscription of RFC 8058 (List-Unsubscribe-Post: List-Unsubscribe=One-Click).
+func (j *Jmessage) ListUnsubscribeOneClick() bool {
+	for _, v := range j.Header["List-Unsubscribe-Post"] {
+		if strings.EqualFold(strings.TrimSpace(v), "List-Unsubscribe=One-Click") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListUnsubscribe(t *testing.T) {
+	eml := "List-Unsubscribe: <mailto:unsub@example.com?subject=unsubscribe>, <https://example.com/unsub?id=123>,\r\n" +
+		"\t<ftp://example.com/unsub>, broken, <https://>, <mailto:>\r\n" +
+		"List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	urls, err := msg.ListUnsubscribe()
+	if err != nil || len(urls) != 2 {
+		t.Fatalf("test: ListUnsubscribe error: (%v, %v)", urls, err)
+	}
+	if urls[0].Scheme != "mailto" || urls[0].Opaque != "unsub@example.com" || urls[0].Query().Get("subject") != "unsubscribe" {
+		t.Errorf("test: ListUnsubscribe mailto error: %s", urls[0])
+	}
+	if urls[1].String() != "https://example.com/unsub?id=123" {
+		t.Errorf("test: ListUnsubscribe https error: %s", urls[1])
+	}
+	if !msg.ListUnsubscribeOneClick() {
+		t.Errorf("test: ListUnsubscribeOneClick error")
+	}
+
+	msg = openTestMessage(t, "./testsubj/00test.eml")
+	if urls, err := msg.ListUnsubscribe(); urls != nil || err != nil {
+		t.Errorf("test: ListUnsubscribe without header error: (%v, %v)", urls, err)
+	}
+	if msg.ListUnsubscribeOneClick() {
+		t.Errorf("test: ListUnsubscribeOneClick without header error")
+	}
+
+	msg, err = ReadMessage(strings.NewReader("List-Unsubscribe: broken\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, err := msg.ListUnsubscribe(); err == nil {
+		t.Errorf("test: ListUnsubscribe should fail without a valid URL")
+	}
+}
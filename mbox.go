@@ -0,0 +1,101 @@
+package jmail
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ReadMbox reads all the messages of an mbox stream. See WalkMbox for the format.
+// Messages that fail to parse are skipped: the others are returned with the
+// error of the first one.
+func ReadMbox(r io.Reader) ([]*Jmessage, error) {
+	var msgs []*Jmessage
+	var parseErr error
+	err := WalkMbox(r, func(msg *Jmessage, err error) error {
+		if err != nil {
+			if parseErr == nil {
+				parseErr = err
+			}
+			return nil
+		}
+		msgs = append(msgs, msg)
+		return nil
+	})
+	if err != nil {
+		return msgs, err
+	}
+	return msgs, parseErr
+}
+
+// WalkMbox calls fn for each message of an mbox stream in order. Messages are
+// separated by the "From " lines, and the ">From " quoting of the body lines is
+// undone (mboxrd). The blank line in front of each "From " line, and at the end
+// of the stream, is part of the separator. Only one message is held in memory
+// at a time. A message that fails to parse is passed to fn with a nil msg and
+// its error, and the walk goes on unless fn returns an error. An error returned
+// by fn stops the walk and is returned by WalkMbox.
+func WalkMbox(r io.Reader, fn func(msg *Jmessage, err error) error) error {
+	br := bufio.NewReader(r)
+	var buf bytes.Buffer
+	// blank は区切りの空行かもしれないので保留している空行
+	var blank []byte
+	started := false
+	n := 0
+	emit := func() error {
+		if !started {
+			return nil
+		}
+		n++
+		msg, err := ParseMessage(buf.Bytes())
+		// msg が buf の中身を参照するので、次のメッセージは新しいバッファに読む
+		buf = bytes.Buffer{}
+		if err != nil {
+			return fn(nil, errors.Wrapf(err, "WalkMbox: message %d: ParseMessage:", n))
+		}
+		return fn(msg, nil)
+	}
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case bytes.HasPrefix(line, []byte("From ")):
+				blank = nil
+				if err := emit(); err != nil {
+					return err
+				}
+				started = true
+			case started:
+				buf.Write(blank)
+				blank = nil
+				if isBlankLine(line) {
+					blank = line
+					break
+				}
+				buf.Write(unquoteMboxFrom(line))
+			}
+		}
+		if err == io.EOF {
+			return emit()
+		}
+		if err != nil {
+			return errors.Wrapf(err, "WalkMbox:")
+		}
+	}
+}
+
+// isBlankLine reports whether line is "\n" or "\r\n".
+func isBlankLine(line []byte) bool {
+	return len(bytes.TrimRight(line, "\r\n")) == 0
+}
+
+// unquoteMboxFrom removes one ">" of a ">From " line (">>From " and so on).
+func unquoteMboxFrom(line []byte) []byte {
+	quoted := bytes.TrimLeft(line, ">")
+	if len(quoted) < len(line) && bytes.HasPrefix(quoted, []byte("From ")) {
+		return line[1:]
+	}
+	return line
+}
@@ -0,0 +1,70 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+const testMbox = "From gopher@example.com Tue Sep 15 16:17:23 2015\n" +
+	"From: Gopher <from@example.com>\n" +
+	"Subject: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?=\n\n" +
+	"first body\n>From the burrow\n>>From deeper\n\n\n" +
+	"From gopher@example.com Wed Sep 16 05:32:04 2015\n" +
+	"From: Another Gopher <to@example.com>\n" +
+	"Subject: second\n\n" +
+	"second body\n"
+
+func TestReadMbox(t *testing.T) {
+	msgs, err := ReadMbox(strings.NewReader(testMbox))
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("test: ReadMbox error: (%d, %v)", len(msgs), err)
+	}
+	if msgs[0].DecSubject() != "ホリネズミ" || msgs[1].DecSubject() != "second" {
+		t.Errorf("test: ReadMbox subject error: (%s, %s)", msgs[0].DecSubject(), msgs[1].DecSubject())
+	}
+	if body, err := msgs[0].DecBody(); err != nil || string(body) != "first body\nFrom the burrow\n>From deeper\n\n" {
+		t.Errorf("test: ReadMbox body error: (%q, %v)", body, err)
+	}
+	if body, err := msgs[1].DecBody(); err != nil || string(body) != "second body\n" {
+		t.Errorf("test: ReadMbox body error: (%q, %v)", body, err)
+	}
+}
+
+func TestWalkMbox(t *testing.T) {
+	stop := errors.New("stop")
+	count := 0
+	err := WalkMbox(strings.NewReader(testMbox), func(msg *Jmessage, err error) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("test: WalkMbox stop error: (%d, %v)", count, err)
+	}
+
+	if msgs, err := ReadMbox(strings.NewReader("")); err != nil || len(msgs) != 0 {
+		t.Errorf("test: ReadMbox empty error: (%d, %v)", len(msgs), err)
+	}
+
+	// 壊れたメッセージがあっても残りを読む
+	broken := "From gopher@example.com Tue Sep 15 16:17:23 2015\n" +
+		"broken header\n\nbody\n\n"
+	var subjs []string
+	var errs []error
+	err = WalkMbox(strings.NewReader(broken+testMbox), func(msg *Jmessage, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		subjs = append(subjs, msg.DecSubject())
+		return nil
+	})
+	if err != nil || len(errs) != 1 || len(subjs) != 2 {
+		t.Errorf("test: WalkMbox broken message error: (%v, %v, %v)", subjs, errs, err)
+	}
+	msgs, err := ReadMbox(strings.NewReader(broken + testMbox))
+	if err == nil || len(msgs) != 2 {
+		t.Errorf("test: ReadMbox broken message error: (%d, %v)", len(msgs), err)
+	}
+}
@@ -1,20 +1,25 @@
 package jmail
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/base64"
 	"io"
-	"io/ioutil"
-	"log"
 	"mime"
 	"mime/multipart"
 	"mime/quotedprintable"
 	"net/mail"
 	"net/textproto"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
-	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
 )
 
@@ -24,12 +29,24 @@ const (
 	SUBJ_PREFIX_UTF8_B      = "=?utf-8?b?"
 	SUBJ_PREFIX_UTF8_Q      = "=?utf-8?q?"
 	CHARSET_ISO2022JP       = "iso-2022-jp"
+	CHARSET_SHIFTJIS        = "shift_jis"
+	CHARSET_WINDOWS31J      = "windows-31j"
+	CHARSET_EUCJP           = "euc-jp"
+	CHARSET_UTF8            = "utf-8"
 	ENC_QUOTED_PRINTABLE    = "quoted-printable"
 	ENC_BASE64              = "base64"
+	ENC_BINARY              = "binary"
 	MEDIATYPE_TEXT          = "text/"
+	MEDIATYPE_TEXT_PLAIN    = "text/plain"
+	MEDIATYPE_TEXT_HTML     = "text/html"
+	MEDIATYPE_TEXT_CALENDAR = "text/calendar"
 	MEDIATYPE_MULTI         = "multipart/"
 	MEDIATYPE_MULTI_REL     = "multipart/related"
 	MEDIATYPE_MULTI_ALT     = "multipart/alternative"
+	MEDIATYPE_MULTI_SIGNED  = "multipart/signed"
+	MEDIATYPE_MULTI_ENC     = "multipart/encrypted"
+	MEDIATYPE_TNEF          = "application/ms-tnef"
+	DEFAULT_CONTENT_TYPE    = "text/plain; charset=us-ascii"
 )
 
 type Message interface {
@@ -38,147 +55,1483 @@ type Message interface {
 	GetFrom() ([]*mail.Address, error)
 	GetTo() ([]*mail.Address, error)
 	GetHeader(string) string
+	HeaderKeys() []string
 }
 
+// ErrNoSubject is returned by DecSubjectErr when the message has no Subject header.
+var ErrNoSubject = errors.New("jmail: no Subject header")
+
+// MaxBodySize is the maximum number of decoded bytes read from a body, attachment or subject.
+// It protects from decoding bombs in untrusted mail.
+var MaxBodySize int64 = 25 << 20
+
+// ErrBodyTooLarge is returned when the decoded data exceeds MaxBodySize, or the
+// raw body buffered for the decoding methods exceeds MaxMessageSize.
+var ErrBodyTooLarge = errors.New("jmail: body too large")
+
+// MaxPartSize is the maximum number of bytes read from a single part of a
+// multipart message, as opposed to MaxBodySize which bounds the decoded data.
+// 0 means no limit.
+var MaxPartSize int64 = 25 << 20
+
+// MaxMessageSize is the maximum number of raw bytes of a body, still encoded,
+// buffered in memory for the decoding methods. 0 means no limit.
+var MaxMessageSize int64 = 100 << 20
+
+// ErrPartTooLarge is the cause of the PartTooLargeError returned when a part
+// exceeds MaxPartSize.
+var ErrPartTooLarge = errors.New("jmail: part too large")
+
+// ErrBodyStreamed is returned when the body was consumed by DecBodyReader,
+// BodyReaderWithMeta or AttachmentReaders before it could be buffered.
+var ErrBodyStreamed = errors.New("jmail: body already streamed")
+
+// MaxHeaderBytes and MaxHeaderLines limit the size of the header block read by ReadMessage.
+var (
+	MaxHeaderBytes int64 = 256 << 10
+	MaxHeaderLines       = 2000
+)
+
+// ErrHeaderTooLarge is returned by ReadMessage when the header block exceeds
+// MaxHeaderBytes or MaxHeaderLines.
+var ErrHeaderTooLarge = errors.New("jmail: header too large")
+
+// MaxDepth is the maximum nesting depth of multipart parts to be parsed.
+var MaxDepth = 50
+
+// ErrTooDeep is returned when multipart parts are nested deeper than MaxDepth.
+var ErrTooDeep = errors.New("jmail: multipart nested too deep")
+
+// DefaultCharset is the charset assumed for text parts without a charset parameter.
+// Empty guesses the charset of such parts with GuessCharset, unless
+// AssumeISO2022JP is false. Set it to
+// CHARSET_ISO2022JP to get the ISO-2022-JP assumption of earlier versions.
+var DefaultCharset = ""
+
+// AssumeISO2022JP makes the text parts without a charset parameter, and with
+// no DefaultCharset, be decoded from the Japanese charset guessed by
+// GuessCharset, ISO-2022-JP when its escape sequences are present. Set it to
+// false to get the bytes of such parts as is, e.g. when the mail handled is
+// not Japanese.
+var AssumeISO2022JP = true
+
+// PermissiveQP makes the text bodies declared 7bit or 8bit (or undeclared) be
+// decoded as quoted-printable when they look like it, as some mailers send.
+// It is a heuristic and off by default.
+var PermissiveQP = false
+
+// RawISO2022JPHeaders makes DecSubject, DecHeader and the display names of
+// addresses decode ISO-2022-JP escape sequences written into headers without
+// encoded-words, as old mailers and mobile phones sent. It is off by default.
+var RawISO2022JPHeaders = false
+
+// ErrNoAddress is returned by GetFromAddr when the message has no From address.
+var ErrNoAddress = errors.New("jmail: no address")
+
+// ErrMissingBoundary is returned when a multipart body has no boundary parameter.
+var ErrMissingBoundary = errors.New("jmail: multipart without boundary")
+
+// ErrEncrypted is returned when the body is multipart/encrypted and has to be decrypted first.
+var ErrEncrypted = errors.New("jmail: encrypted message")
+
+// ErrNoHTMLPart is returned by DecBodyHTML when the message has no text/html part.
+var ErrNoHTMLPart = errors.New("jmail: no text/html part")
+
 // A Jmessage represents a parsed mail message.
 type Jmessage struct {
 	*mail.Message
+	rawHeaders    []byte
+	addressParser *mail.AddressParser
+	warnings      *warningSet
+	// defaultCharset は SetDefaultCharset で設定した charset (nil は DefaultCharset)
+	defaultCharset *string
+	// buffer はメモリに読み込んだ本文 (nil は Body をそのまま読む)
+	buffer *bodyBuffer
+	// raw は ReadMessageRaw で読んだメッセージ全体
+	raw []byte
 }
 
+// ISO-2022-JP, EUC-JP, Shift_JIS (CP932) に対応する
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: wordCharsetReader,
+}
+
+// AddressParser is the default parser of the address headers.
+// Use Jmessage.SetAddressParser to configure a message on its own.
 var AddressParser = mail.AddressParser{
-	//ISO-2022-JP, EUC-JPに対応する
-	WordDecoder: &mime.WordDecoder{
-		CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
-			switch charset {
-			case "iso-2022-jp":
-				return japanese.ISO2022JP.NewDecoder().Reader(input), nil
-			case "euc-jp":
-				return japanese.EUCJP.NewDecoder().Reader(input), nil
-			default:
-				return nil, errors.New("WordDecoder.CharsetReader: Unknown Charset")
-			}
-		},
-	},
+	WordDecoder: wordDecoder,
 }
 
+// ReadMessage reads a message from r. The body is read into memory by the first
+// body method called, e.g. DecBody, so that DecBody, DecBodyHTML, Attachments
+// and the others can be called in any order. Reading Body directly bypasses it.
 func ReadMessage(r io.Reader) (msg *Jmessage, err error) {
-	origmsg, err := mail.ReadMessage(r)
+	var capture headerCapture
+	origmsg, err := mail.ReadMessage(io.TeeReader(r, &capture))
+	if errors.Cause(err) == ErrHeaderTooLarge {
+		err = ErrHeaderTooLarge
+	}
+
+	msg = &Jmessage{Message: origmsg, rawHeaders: capture.header(), warnings: &warningSet{}}
+	if origmsg != nil {
+		msg.buffer = &bodyBuffer{r: origmsg.Body}
+	}
+	return msg, err
+}
 
-	return &Jmessage{origmsg}, err
+// Warnings returns the problems worked around while decoding the message so far:
+// encoded-words DecSubject and DecHeader left undecoded, multipart sections
+// DecBody and DecBodies skipped, and SubstitutionErrors for the text decoded
+// with replacement characters. Nothing is logged; it is up to the caller to look.
+// A problem found again by a later call is recorded once. The warnings are
+// safe to record from concurrent calls.
+func (msg Jmessage) Warnings() []error {
+	if msg.warnings == nil {
+		return nil
+	}
+	return msg.warnings.list()
+}
+
+// warn records errs as warnings of the message.
+func (msg Jmessage) warn(errs ...error) {
+	if msg.warnings != nil {
+		msg.warnings.add(errs...)
+	}
+}
+
+// warningSet holds the warnings of a message, without duplicates.
+type warningSet struct {
+	mu   sync.Mutex
+	errs []error
+	seen map[string]bool
+}
+
+func (w *warningSet) add(errs ...error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, err := range errs {
+		// 同じメソッドを何度呼んでも同じ警告は 1 つだけ
+		if w.seen[err.Error()] {
+			continue
+		}
+		if w.seen == nil {
+			w.seen = make(map[string]bool)
+		}
+		w.seen[err.Error()] = true
+		w.errs = append(w.errs, err)
+	}
+}
+
+func (w *warningSet) list() []error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]error(nil), w.errs...)
 }
 
+// RawHeaders returns the header block exactly as read, up to but not including
+// the blank line separating it from the body. It is meant for DKIM/ARC verification.
+func (msg Jmessage) RawHeaders() []byte {
+	return msg.rawHeaders
+}
+
+// headerCapture keeps the bytes written to it until the end of the header block.
+type headerCapture struct {
+	buf  []byte
+	done bool
+}
+
+func (h *headerCapture) Write(p []byte) (int, error) {
+	if !h.done {
+		h.buf = append(h.buf, p...)
+		if end := headerEnd(h.buf); end >= 0 {
+			h.buf = h.buf[:end]
+			h.done = true
+		}
+		// 巨大なヘッダーは読み込む前に断る
+		if int64(len(h.buf)) > MaxHeaderBytes || bytes.Count(h.buf, []byte("\n")) > MaxHeaderLines {
+			return 0, ErrHeaderTooLarge
+		}
+	}
+	return len(p), nil
+}
+
+func (h *headerCapture) header() []byte {
+	if end := headerEnd(h.buf); end >= 0 {
+		return h.buf[:end]
+	}
+	return h.buf
+}
+
+// headerEnd returns the length of the header block in b, or -1 if b has no blank line.
+func headerEnd(b []byte) int {
+	if bytes.HasPrefix(b, []byte("\r\n")) || bytes.HasPrefix(b, []byte("\n")) {
+		return 0
+	}
+	end := -1
+	for _, sep := range []string{"\n\r\n", "\n\n"} {
+		if i := bytes.Index(b, []byte(sep)); i >= 0 && (end < 0 || i < end) {
+			end = i
+		}
+	}
+	if end < 0 {
+		return -1
+	}
+	return end + 1
+}
+
+// ReadMessageRaw is like ReadMessage, but keeps the complete input in memory,
+// returned by Raw.
+func ReadMessageRaw(r io.Reader) (*Jmessage, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "ReadMessageRaw:")
+	}
+	msg, err := ReadMessage(bytes.NewReader(data))
+	if msg != nil {
+		msg.raw = data
+	}
+	return msg, err
+}
+
+// Raw returns the message exactly as read by ReadMessageRaw, header and body.
+// It is nil for a message read by ReadMessage.
+func (msg Jmessage) Raw() []byte {
+	return msg.raw
+}
+
+// ParseMessage parses a message held in memory.
+func ParseMessage(data []byte) (*Jmessage, error) {
+	return ReadMessage(bytes.NewReader(data))
+}
+
+// DecSubject returns the Subject with its RFC 2047 encoded-words decoded to UTF-8.
+// The whitespace between unencoded words is kept as is, except at the line folds,
+// which net/mail already replaced with a single space.
 func (msg Jmessage) DecSubject() string {
-	header := msg.Header
-	splitsubj := strings.Fields(header.Get("Subject"))
+	return msg.DecHeader("Subject")
+}
+
+// SubjectIsJapanese reports whether the decoded Subject contains Hiragana,
+// Katakana (including the half-width forms) or Kanji.
+func (msg Jmessage) SubjectIsJapanese() bool {
+	return containsJapanese(msg.DecSubject())
+}
+
+func containsJapanese(text string) bool {
+	for _, r := range text {
+		// 長音符 "ー" は Common に分類されている
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) || r == 'ー' {
+			return true
+		}
+	}
+	return false
+}
+
+// DecHeader returns the value of the header key with its RFC 2047 encoded-words
+// decoded in the same way as DecSubject.
+func (msg Jmessage) DecHeader(key string) string {
+	value := msg.Header.Get(key)
+	decoded, undecoded := decodeHeaderWarn(value)
+	for _, word := range undecoded {
+		msg.warn(errors.Errorf("jmail: %s: undecodable encoded-word %q", key, word))
+	}
+	if n := substitutions(decoded) - substitutions(value); n > 0 {
+		msg.warn(&SubstitutionError{Where: key, Count: n})
+	}
+	return decoded
+}
+
+// DecSubjectErr is like DecSubject, but returns ErrNoSubject when the
+// Subject header is absent, so it can be told apart from an empty subject.
+func (msg Jmessage) DecSubjectErr() (string, error) {
+	if _, ok := msg.Header["Subject"]; !ok {
+		return "", ErrNoSubject
+	}
+	return msg.DecSubject(), nil
+}
+
+// decodeHeader decodes the RFC 2047 encoded-words in a header value.
+// Whitespace between two encoded-words is dropped, other whitespace is kept as is.
+// Well-formed values are decoded by mime.WordDecoder; the others fall back to
+// decodeHeaderLenient.
+func decodeHeader(value string) string {
+	decoded, _ := decodeHeaderWarn(value)
+	return decoded
+}
+
+// decodeHeaderWarn is decodeHeader also returning the encoded-words left as is.
+func decodeHeaderWarn(value string) (string, []string) {
+	value = unfold(value)
+	if !strings.Contains(value, "=?") {
+		// encoded-word がなければデコード不要
+		return decodeRawISO2022JP(value), nil
+	}
+	if decoded, err := wordDecoder.DecodeHeader(value); err == nil && !strings.Contains(decoded, "=?") {
+		return decodeRawISO2022JP(decoded), nil
+	}
+	decoded, undecoded := decodeHeaderLenient(value)
+	return decodeRawISO2022JP(decoded), undecoded
+}
+
+// decodeRawISO2022JP decodes the ISO-2022-JP written into value without
+// encoded-words when RawISO2022JPHeaders is set. Address headers are decoded
+// before parsing, since the escape sequences can hold ":" and other specials.
+func decodeRawISO2022JP(value string) string {
+	if !RawISO2022JPHeaders || !strings.Contains(value, "\x1b$") {
+		return value
+	}
+	decoded, err := DecodeCharset(CHARSET_ISO2022JP, []byte(value))
+	if err != nil {
+		return value
+	}
+	return string(decoded)
+}
+
+// decodeHeaderLenient decodes the encoded-words of an unfolded header value
+// tolerating the broken forms seen in Japanese mail: words split by folding,
+// missing "?=", unpadded base64 and unknown charsets, which are kept as is and
+// returned in undecoded.
+func decodeHeaderLenient(value string) (string, []string) {
 	var bufSubj bytes.Buffer
-	for seq, parts := range splitsubj {
-		switch {
-		case !strings.HasPrefix(parts, "=?"):
-			// エンコードなし
-			if seq > 0 {
-				// 先頭以外はSpaceで区切りなおし
-				bufSubj.WriteByte(' ')
+	var undecoded []string
+	prevEncoded := false
+	for value != "" {
+		// 空白と単語に区切る
+		n := len(value) - len(strings.TrimLeft(value, " \t"))
+		space := value[:n]
+		value = value[n:]
+		end := strings.IndexAny(value, " \t")
+		if end < 0 {
+			end = len(value)
+		}
+		parts := value[:end]
+		value = value[end:]
+
+		if strings.HasPrefix(parts, "=?") && !isEncodedWord(parts) {
+			// 折り返しで分断された encoded-word をつなぎ直す
+			if joined, rest, ok := joinEncodedWord(parts, value); ok {
+				parts, value = joined, rest
 			}
+		}
+		decoded, encoded := decodeWord(parts)
+		if !encoded || !prevEncoded {
+			// encoded-word 同士の間の空白は取り除く
+			bufSubj.WriteString(space)
+		}
+		prevEncoded = encoded
+
+		if encoded {
+			bufSubj.Write(decoded)
+		} else {
+			// エンコードなし、または未知の charset はそのまま
 			bufSubj.WriteString(parts)
+			if strings.HasPrefix(parts, "=?") {
+				undecoded = append(undecoded, parts)
+			}
+		}
+	}
+	return bufSubj.String(), undecoded
+}
 
-		case len(parts) > len(SUBJ_PREFIX_ISO2022JP_B) && strings.HasPrefix(strings.ToLower(parts[0:len(SUBJ_PREFIX_ISO2022JP_B)]), SUBJ_PREFIX_ISO2022JP_B):
-			// iso-2022-jp / base64
-			beforeDecode := parts[len(SUBJ_PREFIX_ISO2022JP_B):strings.LastIndex(parts, "?=")]
-			afterDecode := base64.NewDecoder(base64.StdEncoding, bytes.NewBufferString(beforeDecode))
-			subj_bytes, _ := ioutil.ReadAll(transform.NewReader(afterDecode, japanese.ISO2022JP.NewDecoder()))
-			bufSubj.Write(subj_bytes)
+// unfold removes the line breaks of a folded header value.
+func unfold(value string) string {
+	if strings.IndexByte(value, '\n') < 0 {
+		return value
+	}
+	return unfolder.Replace(value)
+}
 
-		case len(parts) > len(SUBJ_PREFIX_ISO2022JP_Q) && strings.HasPrefix(strings.ToLower(parts[0:len(SUBJ_PREFIX_ISO2022JP_Q)]), SUBJ_PREFIX_ISO2022JP_Q):
-			// iso-2022-jp / quoted-printable
-			beforeDecode := parts[len(SUBJ_PREFIX_ISO2022JP_Q):strings.LastIndex(parts, "?=")]
-			afterDecode := quotedprintable.NewReader(bytes.NewBufferString(beforeDecode))
-			subj_bytes, _ := ioutil.ReadAll(transform.NewReader(afterDecode, japanese.ISO2022JP.NewDecoder()))
-			bufSubj.Write(subj_bytes)
+var unfolder = strings.NewReplacer("\r\n ", " ", "\r\n\t", "\t", "\n ", " ", "\n\t", "\t")
 
-		case len(parts) > len(SUBJ_PREFIX_UTF8_B) && strings.HasPrefix(strings.ToLower(parts[0:len(SUBJ_PREFIX_UTF8_B)]), SUBJ_PREFIX_UTF8_B):
-			// utf-8 / base64
-			beforeDecode := parts[len(SUBJ_PREFIX_UTF8_B):strings.LastIndex(parts, "?=")]
-			subj_bytes, _ := base64.StdEncoding.DecodeString(beforeDecode)
-			bufSubj.Write(subj_bytes)
+// isEncodedWord reports whether word has the form =?charset?encoding?text?=.
+func isEncodedWord(word string) bool {
+	return len(word) > len("=???=") && strings.HasPrefix(word, "=?") && strings.HasSuffix(word, "?=") && strings.Count(word, "?") >= 4
+}
+
+// decodeWord decodes an encoded-word with the charset and encoding declared in it.
+// A word missing the trailing "?=" is decoded up to its end.
+// ok is false when word is not an encoded-word or its charset or encoding is unknown.
+func decodeWord(word string) (decoded []byte, ok bool) {
+	if !strings.HasPrefix(word, "=?") {
+		return nil, false
+	}
+	// 閉じの ?= がない壊れた encoded-word (一部の携帯キャリア) は残り全体を本文とみなす
+	fields := strings.SplitN(strings.TrimSuffix(word[len("=?"):], "?="), "?", 3)
+	if len(fields) != 3 {
+		return nil, false
+	}
+	// RFC 2231 の言語指定 (charset*lang) は無視する
+	charset := strings.SplitN(fields[0], "*", 2)[0]
+	text := fields[2]
+	var r io.Reader
+	switch strings.ToLower(fields[1]) {
+	case "b":
+		// 単語の長さの入力バッファで足りる
+		r = &base64Reader{r: strings.NewReader(text), in: make([]byte, len(text)+1)}
+	case "q":
+		// Q encoding では "_" は空白を表す (行末の空白が落ちないよう =20 にする)
+		r = quotedprintable.NewReader(strings.NewReader(strings.Replace(text, "_", "=20", -1)))
+	default:
+		return nil, false
+	}
+	dec, err := charsetDecoder(charset)
+	if err != nil {
+		return nil, false
+	}
+	decoded, _ = readAllLimited(r, MaxBodySize)
+	if dec != transform.Nop {
+		// 短いので transform.Reader を使わずに一度に変換する
+		decoded, _, _ = transform.Bytes(dec, decoded)
+	}
+	return decoded, true
+}
 
-		case len(parts) > len(SUBJ_PREFIX_UTF8_Q) && strings.HasPrefix(strings.ToLower(parts[0:len(SUBJ_PREFIX_UTF8_Q)]), SUBJ_PREFIX_UTF8_Q):
-			// utf-8 / quoted-printable
-			beforeDecode := parts[len(SUBJ_PREFIX_UTF8_Q):strings.LastIndex(parts, "?=")]
-			afterDecode := quotedprintable.NewReader(bytes.NewBufferString(beforeDecode))
-			subj_bytes, _ := ioutil.ReadAll(afterDecode)
-			bufSubj.Write(subj_bytes)
+// joinEncodedWord appends the following words to the incomplete encoded-word until
+// it is terminated with "?=". ok is false when no terminator is found.
+func joinEncodedWord(word, rest string) (joined, remain string, ok bool) {
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(rest, "=?") {
+			// 次の encoded-word が始まった
+			break
+		}
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		word += rest[:end]
+		rest = rest[end:]
+		if isEncodedWord(word) {
+			return word, rest, true
 		}
 	}
-	return bufSubj.String()
+	return "", "", false
 }
 
 func (msg Jmessage) DecBody() ([]byte, error) {
-	return getText(msg.Header, msg.Body)
+	text, _, err := msg.DecBodyWithCharset()
+	return text, err
 }
 
-func getText(header mail.Header, body io.Reader) ([]byte, error) {
+// DecBodyString is like DecBody, but returns a string. The string is always
+// valid UTF-8: invalid sequences, such as the ones of a broken or mislabeled
+// charset, are replaced with U+FFFD.
+func (msg Jmessage) DecBodyString() (string, error) {
+	body, err := msg.DecBody()
+	return strings.ToValidUTF8(string(body), "\uFFFD"), err
+}
+
+// DecBodyNormalized is like DecBody, but converts the CRLF and CR line endings
+// of the text body to LF. Attachments are never part of the text body.
+func (msg Jmessage) DecBodyNormalized() ([]byte, error) {
+	body, err := msg.DecBody()
+	return normalizeNewlines(body), err
+}
+
+// normalizeNewlines converts CRLF and CR to LF.
+func normalizeNewlines(text []byte) []byte {
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(text, []byte("\r"), []byte("\n"))
+}
+
+// DecBodyWithCharset is like DecBody, but also returns the charset the body was
+// decoded from: the declared one, or DefaultCharset or the guessed one when the
+// body declares none.
+func (msg Jmessage) DecBodyWithCharset() (body []byte, charset string, err error) {
+	body, charset, partErrs, err := getText(msg.Header, msg.body(), msg.fallbackCharset(), 0)
+	if err == nil {
+		msg.warn(partErrs...)
+	}
+	if _, cerr := charsetDecoder(charset); charset != "" && cerr != nil {
+		// 未知の charset はデコードせずに返している
+		msg.warn(cerr)
+	}
+	if n := substitutions(string(body)); n > 0 {
+		msg.warn(&SubstitutionError{Where: "body", Charset: charset, Count: n})
+	}
+	return body, charset, err
+}
+
+// DecBodyReader returns a reader streaming the decoded text body, without
+// buffering the whole message. Unlike DecBody, decode errors are reported by Read.
+// Called before any other body method, it consumes the body: the body methods
+// called afterwards return ErrBodyStreamed.
+func (msg Jmessage) DecBodyReader() (io.ReadCloser, error) {
+	r, _, _, err := msg.BodyReaderWithMeta()
+	return r, err
+}
+
+// BodyReaderWithMeta is like DecBodyReader, but also returns the media type of
+// the text part and the charset it is decoded from, e.g. for a Content-Type
+// header. The output of r is always UTF-8, unless the charset is unknown.
+func (msg Jmessage) BodyReaderWithMeta() (r io.ReadCloser, mediatype string, charset string, err error) {
+	tr, mediatype, charset, err := textReader(msg.Header, msg.streamBody(), msg.fallbackCharset(), 0)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return io.NopCloser(tr), mediatype, charset, nil
+}
+
+// textReader returns a decoding reader for the first text part, with its media
+// type and charset. io.EOF means no text part. Broken multipart sections are
+// skipped like in getText, and returned as PartErrors when no text part is found.
+func textReader(header mail.Header, body io.Reader, fallback string, depth int) (io.Reader, string, string, error) {
 	contentType := header.Get("Content-Type")
-	if contentType == "" || strings.HasPrefix(contentType, MEDIATYPE_TEXT) {
-		return readPlainText(map[string][]string(header), body)
+	if contentType == "" || strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), MEDIATYPE_TEXT) {
+		mediatype, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			// Content-Type なし、または壊れている
+			mediatype = MEDIATYPE_TEXT_PLAIN
+		}
+		r, charset := textCharsetReader(map[string][]string(header), body, fallback)
+		return r, mediatype, charset, nil
 	}
-	_, params, err := mime.ParseMediaType(contentType)
+	mediatype, params, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return nil, errors.Wrapf(err, "getText: ParseMediaType:")
+		return nil, "", "", errors.Wrapf(err, "textReader: ParseMediaType:")
+	}
+	if !strings.HasPrefix(mediatype, MEDIATYPE_MULTI) {
+		return nil, "", "", io.EOF
+	}
+	if mediatype == MEDIATYPE_MULTI_ENC {
+		return nil, "", "", ErrEncrypted
+	}
+	if params["boundary"] == "" {
+		return nil, "", "", ErrMissingBoundary
 	}
-	mr := multipart.NewReader(body, params["boundary"])
+	if depth >= MaxDepth {
+		return nil, "", "", ErrTooDeep
+	}
+	mr := newPartReader(mediatype, body, params)
+	var partErrs PartErrors
+	var attached io.Reader
+	var attachedType, attachedCharset string
 	for {
 		p, err := mr.NextPart()
+		if err == io.EOF && attached != nil {
+			return attached, attachedType, attachedCharset, nil
+		}
+		if err == io.EOF && len(partErrs) > 0 {
+			return nil, "", "", partErrs
+		}
+		if err != nil {
+			return nil, "", "", err
+		}
+		if isAttachedText(mail.Header(p.Header)) {
+			// 添付されたテキストは本文が見つからないときだけ使う
+			if attached == nil {
+				r, mediatype, charset, err := textReader(mail.Header(p.Header), p, fallback, depth+1)
+				if err != nil {
+					continue
+				}
+				text, err := readAllLimited(r, MaxBodySize)
+				if err != nil {
+					continue
+				}
+				attached, attachedType, attachedCharset = bytes.NewReader(text), mediatype, charset
+			}
+			continue
+		}
+		r, mediatype, charset, err := textReader(mail.Header(p.Header), p, fallback, depth+1)
 		if err == io.EOF {
-			return nil, err
+			continue
+		}
+		if err == ErrTooDeep || errors.Cause(err) == ErrPartTooLarge {
+			return nil, "", "", err
+		}
+		if errs, ok := err.(PartErrors); ok {
+			partErrs = append(partErrs, errs...)
+			continue
 		}
 		if err != nil {
-			return nil, err
+			// getText と同じく壊れたセクションは飛ばす
+			partErrs = append(partErrs, err)
+			continue
+		}
+		return r, mediatype, charset, nil
+	}
+}
+
+// DecBodyPartial is like DecBody, but also returns the errors of the
+// multipart sections that were skipped on the way to the body.
+// When no section could be decoded at all, err is the PartErrors itself.
+func (msg Jmessage) DecBodyPartial() (body []byte, partErrs PartErrors, err error) {
+	body, _, partErrs, err = getText(msg.Header, msg.body(), msg.fallbackCharset(), 0)
+	return body, partErrs, err
+}
+
+// partReader is a multipart.Reader that stops after the first part of
+// multipart/signed, leaving out the signature.
+type partReader struct {
+	*multipart.Reader
+	body   io.Reader
+	signed bool
+	n      int
+}
+
+func newPartReader(mediatype string, body io.Reader, params map[string]string) *partReader {
+	return &partReader{
+		Reader: multipart.NewReader(body, params["boundary"]),
+		body:   body,
+		signed: mediatype == MEDIATYPE_MULTI_SIGNED,
+	}
+}
+
+func (r *partReader) NextPart() (*limitedPart, error) {
+	if r.signed && r.n > 0 {
+		// 2 つ目は署名
+		return nil, io.EOF
+	}
+	r.n++
+	p, err := r.Reader.NextPart()
+	if lp, ok := r.body.(*limitedPart); ok && lp.exceeded {
+		// multipart.Reader はエラーを文字列にしてしまう
+		return nil, &PartTooLargeError{Index: lp.index}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &limitedPart{Part: p, index: r.n}, nil
+}
+
+// limitedPart is a multipart.Part whose Read fails with a PartTooLargeError
+// after MaxPartSize bytes.
+type limitedPart struct {
+	*multipart.Part
+	index    int
+	n        int64
+	exceeded bool
+}
+
+func (p *limitedPart) Read(b []byte) (int, error) {
+	if MaxPartSize > 0 && p.n >= MaxPartSize {
+		// 上限ちょうどで終わるパートは許す
+		var one [1]byte
+		if n, err := p.Part.Read(one[:]); n == 0 {
+			return 0, err
 		}
-		text, err := getText(mail.Header(p.Header), p)
+		p.exceeded = true
+		return 0, &PartTooLargeError{Index: p.index}
+	}
+	if MaxPartSize > 0 && int64(len(b)) > MaxPartSize-p.n {
+		b = b[:MaxPartSize-p.n]
+	}
+	n, err := p.Part.Read(b)
+	p.n += int64(n)
+	return n, err
+}
+
+// A PartTooLargeError is returned when a part of a multipart message is larger
+// than MaxPartSize. errors.Cause returns ErrPartTooLarge for it.
+type PartTooLargeError struct {
+	Index int // the 1-based index of the part in its multipart
+}
+
+func (e *PartTooLargeError) Error() string {
+	return ErrPartTooLarge.Error() + ": part " + strconv.Itoa(e.Index)
+}
+
+func (e *PartTooLargeError) Cause() error {
+	return ErrPartTooLarge
+}
+
+func (e *PartTooLargeError) Unwrap() error {
+	return ErrPartTooLarge
+}
+
+// A SubstitutionError is recorded in Warnings when a decoded header or body
+// holds U+FFFD replacement characters or invalid UTF-8, i.e. some of its bytes
+// could not be decoded and the text needs a manual review.
+type SubstitutionError struct {
+	Where   string // "body" or the header key
+	Charset string // the body charset, if known
+	Count   int    // the number of replaced or invalid characters
+}
+
+func (e *SubstitutionError) Error() string {
+	msg := "jmail: " + e.Where + ": " + strconv.Itoa(e.Count) + " undecodable characters"
+	if e.Charset != "" {
+		msg += " (" + e.Charset + ")"
+	}
+	return msg
+}
+
+// substitutions counts the U+FFFD and the invalid UTF-8 bytes in text.
+func substitutions(text string) int {
+	n := 0
+	for _, r := range text {
+		if r == utf8.RuneError {
+			n++
+		}
+	}
+	return n
+}
+
+// PartErrors holds the errors of multipart sections that failed to decode.
+type PartErrors []error
+
+func (e PartErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "jmail: failed parse multipart: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of each section.
+func (e PartErrors) Unwrap() []error {
+	return e
+}
+
+func getText(header mail.Header, body io.Reader, fallback string, depth int) ([]byte, string, PartErrors, error) {
+	contentType := header.Get("Content-Type")
+	if contentType == "" || strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), MEDIATYPE_TEXT) {
+		text, charset, err := readText(map[string][]string(header), body, fallback)
+		return text, charset, nil, err
+	}
+	mediatype, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", nil, errors.Wrapf(err, "getText: ParseMediaType:")
+	}
+	if !strings.HasPrefix(mediatype, MEDIATYPE_MULTI) {
+		// 画像などテキストでないパート
+		return nil, "", nil, io.EOF
+	}
+	if mediatype == MEDIATYPE_MULTI_ENC {
+		return nil, "", nil, ErrEncrypted
+	}
+	if params["boundary"] == "" {
+		return nil, "", nil, ErrMissingBoundary
+	}
+	if depth >= MaxDepth {
+		return nil, "", nil, ErrTooDeep
+	}
+	mr := newPartReader(mediatype, body, params)
+	var partErrs PartErrors
+	var attached []byte
+	var attachedCharset string
+	for {
+		p, err := mr.NextPart()
 		if err == io.EOF {
+			if attached != nil {
+				return attached, attachedCharset, partErrs, nil
+			}
+			if len(partErrs) > 0 {
+				return nil, "", partErrs, partErrs
+			}
+			return nil, "", nil, err
+		}
+		if err != nil {
+			return nil, "", partErrs, err
+		}
+		if isAttachedText(mail.Header(p.Header)) {
+			// 添付されたテキストは本文が見つからないときだけ使う
+			if attached == nil {
+				text, charset, err := readText(map[string][]string(p.Header), p, fallback)
+				if errors.Cause(err) == ErrPartTooLarge {
+					return nil, "", partErrs, err
+				}
+				if err != nil {
+					partErrs = append(partErrs, err)
+					if !isPartialText(text, err) {
+						continue
+					}
+				}
+				attached, attachedCharset = text, charset
+			}
 			continue
 		}
+		text, charset, errs, err := getText(mail.Header(p.Header), p, fallback, depth+1)
+		partErrs = append(partErrs, errs...)
+		if err == io.EOF {
+			continue
+		}
+		if err == ErrTooDeep || errors.Cause(err) == ErrPartTooLarge {
+			return nil, "", partErrs, err
+		}
+		if isPartialText(text, err) {
+			// 単一パートと同じく、デコードできた分をエラーと一緒に返す
+			return text, charset, partErrs, err
+		}
 		if err != nil {
-			log.Println("[WARN] dozen/jmail: failed parse multipart:", err)
+			if _, ok := err.(PartErrors); !ok {
+				partErrs = append(partErrs, err)
+			}
 			continue
 		}
-		return text, err
+		return text, charset, partErrs, nil
 	}
 }
 
-// Read body from text/plain
-func readPlainText(header textproto.MIMEHeader, body io.Reader) (mailbody []byte, err error) {
+// isAttachedText reports whether a part is a text file attached with
+// Content-Disposition: attachment, rather than a text body.
+func isAttachedText(header mail.Header) bool {
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if disposition != DISPOSITION_ATTACHMENT {
+		return false
+	}
+	contentType := strings.ToLower(strings.TrimSpace(header.Get("Content-Type")))
+	return contentType == "" || strings.HasPrefix(contentType, MEDIATYPE_TEXT)
+}
+
+// ContentType returns the media type and parameters of the Content-Type header.
+// A missing header defaults to text/plain; charset=us-ascii as RFC 2045 requires.
+func (msg Jmessage) ContentType() (mediatype string, params map[string]string, err error) {
+	return parseContentType(msg.Header)
+}
+
+func parseContentType(header mail.Header) (mediatype string, params map[string]string, err error) {
 	contentType := header.Get("Content-Type")
-	encoding := header.Get("Content-Transfer-Encoding")
+	if contentType == "" {
+		contentType = DEFAULT_CONTENT_TYPE
+	}
+	mediatype, params, err = mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "ParseMediaType:")
+	}
+	return mediatype, params, nil
+}
+
+// IsMultipart reports whether the message is a multipart/* message.
+func (msg Jmessage) IsMultipart() bool {
+	mediatype, _, err := parseContentType(msg.Header)
+	return err == nil && strings.HasPrefix(mediatype, MEDIATYPE_MULTI)
+}
+
+// PartCount returns the number of immediate child parts of a multipart message,
+// not counting the parts nested in them. It is 0 for a non-multipart message.
+// Like DecBody, it reads the message body.
+func (msg Jmessage) PartCount() (int, error) {
+	mediatype, params, err := parseContentType(msg.Header)
+	if err != nil {
+		return 0, errors.Wrapf(err, "PartCount:")
+	}
+	if !strings.HasPrefix(mediatype, MEDIATYPE_MULTI) {
+		return 0, nil
+	}
+	if params["boundary"] == "" {
+		return 0, ErrMissingBoundary
+	}
+	mr := multipart.NewReader(msg.body(), params["boundary"])
+	for n := 0; ; n++ {
+		_, err := mr.NextRawPart()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, errors.Wrapf(err, "PartCount: NextRawPart:")
+		}
+	}
+}
+
+// DecBodyHTML returns the decoded text/html part of the message,
+// preferring it over text/plain inside multipart/alternative and multipart/related.
+// Broken multipart sections are skipped, like in DecBody.
+func (msg Jmessage) DecBodyHTML() ([]byte, error) {
+	return getHTML(msg.Header, msg.body(), msg.fallbackCharset(), 0)
+}
+
+func getHTML(header mail.Header, body io.Reader, fallback string, depth int) ([]byte, error) {
+	mediatype, params, err := parseContentType(header)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getHTML:")
+	}
+	switch {
+	case mediatype == MEDIATYPE_TEXT_HTML:
+		html, _, err := readText(map[string][]string(header), body, fallback)
+		return html, err
+	case mediatype == MEDIATYPE_MULTI_ENC:
+		return nil, ErrEncrypted
+	case strings.HasPrefix(mediatype, MEDIATYPE_MULTI):
+		if params["boundary"] == "" {
+			return nil, ErrMissingBoundary
+		}
+		if depth >= MaxDepth {
+			return nil, ErrTooDeep
+		}
+		mr := newPartReader(mediatype, body, params)
+		var attached []byte
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF && attached != nil {
+				return attached, nil
+			}
+			if err == io.EOF {
+				return nil, ErrNoHTMLPart
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "getHTML: NextPart:")
+			}
+			html, err := getHTML(mail.Header(p.Header), p, fallback, depth+1)
+			if err == nil && isAttachedText(mail.Header(p.Header)) {
+				// 添付された HTML は本文が見つからないときだけ使う
+				if attached == nil {
+					attached = html
+				}
+				continue
+			}
+			if err == ErrNoHTMLPart {
+				continue
+			}
+			if err == ErrTooDeep || errors.Cause(err) == ErrPartTooLarge || isPartialText(html, err) {
+				return html, err
+			}
+			if err != nil {
+				// getText と同じく壊れたセクションは飛ばす
+				continue
+			}
+			return html, nil
+		}
+	}
+	return nil, ErrNoHTMLPart
+}
+
+// DecBodies returns both the decoded text/plain and text/html parts, walking the
+// multipart tree only once. A part that the message doesn't have is returned as nil.
+func (msg Jmessage) DecBodies() (plain []byte, html []byte, err error) {
+	b := bodies{fallback: msg.fallbackCharset()}
+	err = b.walk(msg.Header, msg.body(), 0)
+	if b.plain == nil {
+		b.plain = b.attachedPlain
+	}
+	if b.html == nil {
+		b.html = b.attachedHTML
+	}
+	if err == nil && b.plain == nil && b.html == nil && len(b.errs) > 0 {
+		err = b.errs
+	} else if err == nil {
+		msg.warn(b.errs...)
+	}
+	if n := substitutions(string(b.plain)) + substitutions(string(b.html)); n > 0 {
+		msg.warn(&SubstitutionError{Where: "body", Count: n})
+	}
+	return b.plain, b.html, err
+}
+
+// PreferredText returns the text/html part when preferHTML and the message has
+// one, or else the text/plain part, falling back to the other representation
+// when the preferred one is missing. mediatype tells which one body is.
+// A message with neither returns io.EOF, like DecBody.
+func (msg Jmessage) PreferredText(preferHTML bool) (body []byte, mediatype string, err error) {
+	plain, html, err := msg.DecBodies()
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case html != nil && (preferHTML || plain == nil):
+		return html, MEDIATYPE_TEXT_HTML, nil
+	case plain != nil:
+		return plain, MEDIATYPE_TEXT_PLAIN, nil
+	}
+	return nil, "", io.EOF
+}
+
+// bodies collects the first text/plain and text/html parts.
+type bodies struct {
+	plain    []byte
+	html     []byte
+	errs     PartErrors
+	fallback string
+	// 添付されたテキスト (本文がないときに使う)
+	attachedPlain []byte
+	attachedHTML  []byte
+}
+
+func (b *bodies) walk(header mail.Header, body io.Reader, depth int) error {
+	mediatype, params, err := parseContentType(header)
+	if err != nil {
+		return errors.Wrapf(err, "DecBodies:")
+	}
+	switch {
+	case mediatype == MEDIATYPE_TEXT_PLAIN && b.plain == nil, mediatype == MEDIATYPE_TEXT_HTML && b.html == nil:
+		attached := depth > 0 && isAttachedText(header)
+		if attached && (mediatype == MEDIATYPE_TEXT_PLAIN && b.attachedPlain != nil || mediatype == MEDIATYPE_TEXT_HTML && b.attachedHTML != nil) {
+			return nil
+		}
+		text, _, err := readText(map[string][]string(header), body, b.fallback)
+		if errors.Cause(err) == ErrPartTooLarge {
+			return err
+		}
+		if err != nil {
+			b.errs = append(b.errs, err)
+			if !isPartialText(text, err) {
+				return nil
+			}
+		}
+		switch {
+		case attached && mediatype == MEDIATYPE_TEXT_PLAIN:
+			b.attachedPlain = text
+		case attached:
+			b.attachedHTML = text
+		case mediatype == MEDIATYPE_TEXT_PLAIN:
+			b.plain = text
+		default:
+			b.html = text
+		}
+
+	case mediatype == MEDIATYPE_MULTI_ENC:
+		return ErrEncrypted
+	case strings.HasPrefix(mediatype, MEDIATYPE_MULTI):
+		if params["boundary"] == "" {
+			return ErrMissingBoundary
+		}
+		if depth >= MaxDepth {
+			return ErrTooDeep
+		}
+		mr := newPartReader(mediatype, body, params)
+		for b.plain == nil || b.html == nil {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrapf(err, "DecBodies: NextPart:")
+			}
+			if err := b.walk(mail.Header(p.Header), p, depth+1); err == ErrTooDeep || errors.Cause(err) == ErrPartTooLarge {
+				return err
+			} else if err != nil {
+				b.errs = append(b.errs, err)
+			}
+		}
+	}
+	return nil
+}
+
+// readText reads a text body decoded to UTF-8, and returns the charset it was
+// decoded from. fallback is the charset of a body without a charset parameter.
+func readText(header textproto.MIMEHeader, body io.Reader, fallback string) ([]byte, string, error) {
+	r, charset := textCharsetReader(header, body, fallback)
+	mailbody, err := readAllLimited(r, MaxBodySize)
+	return mailbody, charset, errors.Wrapf(err, "readText:")
+}
+
+// textCharsetReader returns a reader decoding a text body in two independent steps:
+// the transfer encoding (and Content-Encoding) first, then the charset. The lines
+// of format=flowed text are unwrapped last. It also returns the charset used: the
+// declared one, fallback or the guessed one. A binary body only uses the declared
+// charset.
+func textCharsetReader(header textproto.MIMEHeader, body io.Reader, fallback string) (io.Reader, string) {
+	// 1. Content-Transfer-Encoding, Content-Encoding を戻す
+	encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding")))
+	if PermissiveQP && encoding != ENC_QUOTED_PRINTABLE && encoding != ENC_BASE64 && encoding != ENC_BINARY {
+		// 7bit/8bit と宣言された quoted-printable
+		br := bufio.NewReaderSize(body, guessSize)
+		head, _ := br.Peek(guessSize)
+		body = br
+		if looksQuotedPrintable(head) {
+			encoding = ENC_QUOTED_PRINTABLE
+		}
+	}
+	body = transferDecoder(encoding, body)
+	body = contentDecoder(header.Get("Content-Encoding"), body)
+
+	// 2. charset から UTF-8 に変換する (未知の charset はそのまま)
+	charset := bodyCharset(header, fallback)
+	if encoding == ENC_BINARY {
+		// binary は宣言された charset だけを使い、推測しない
+		charset = charsetParam(header.Get("Content-Type"))
+	}
+	if charset == "" && AssumeISO2022JP && encoding != ENC_BINARY {
+		// charset 指定なしは先頭から推測する
+		br := bufio.NewReaderSize(body, guessSize)
+		head, _ := br.Peek(guessSize)
+		charset, body = GuessCharset(head), br
+	}
+	if r, err := newCharsetReader(charset, body); err == nil {
+		body = r
+	}
+
+	// 3. format=flowed の折り返しを戻す
+	if flowed, delsp := isFlowed(header); flowed {
+		body = newFlowedReader(body, delsp)
+	}
+	return body, charset
+}
+
+// looksQuotedPrintable reports whether every "=" in data starts a =XX escape or
+// a soft line break, and there are at least 3 of them.
+func looksQuotedPrintable(data []byte) bool {
+	n, cut := 0, len(data) == guessSize
+	for i := bytes.IndexByte(data, '='); i >= 0; i = bytes.IndexByte(data, '=') {
+		rest := data[i+1:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("\r\n")), bytes.HasPrefix(rest, []byte("\n")):
+		case len(rest) >= 2 && isUpperHex(rest[0]) && isUpperHex(rest[1]):
+		case len(rest) < 2 && cut:
+			// 先頭部分の末尾で切れた
+		default:
+			return false
+		}
+		n++
+		data = rest
+	}
+	return n >= 3
+}
+
+func isUpperHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'A' <= c && c <= 'F'
+}
+
+// guessSize is the number of bytes at the head of a body used to guess its charset.
+const guessSize = 8 << 10
+
+// bodyCharset returns the lower-cased charset of a text body.
+// A body without a charset parameter gets fallback.
+func bodyCharset(header textproto.MIMEHeader, fallback string) string {
+	charset := charsetParam(header.Get("Content-Type"))
+	if charset == "" {
+		// charset 指定なしは fallback (DefaultCharset)
+		charset = strings.ToLower(fallback)
+	}
+	return charset
+}
+
+// charsetParam returns the lower-cased charset parameter of a Content-Type value.
+// Stray quotes and spaces around the value are removed, and a value that
+// mime.ParseMediaType rejects (e.g. an unterminated quote) is scanned by hand.
+func charsetParam(contentType string) string {
 	_, params, err := mime.ParseMediaType(contentType)
-	if encoding == ENC_QUOTED_PRINTABLE {
-		if strings.ToLower(params["charset"]) == CHARSET_ISO2022JP {
-			mailbody, err = ioutil.ReadAll(transform.NewReader(quotedprintable.NewReader(body), japanese.ISO2022JP.NewDecoder()))
-		} else {
-			mailbody, err = ioutil.ReadAll(quotedprintable.NewReader(body))
+	charset := params["charset"]
+	if err != nil {
+		for _, param := range strings.Split(contentType, ";")[1:] {
+			if i := strings.IndexByte(param, '='); i >= 0 && strings.EqualFold(strings.TrimSpace(param[:i]), "charset") {
+				charset = param[i+1:]
+				break
+			}
 		}
-	} else if encoding == ENC_BASE64 {
-		mailbody, err = ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, body))
-	} else if len(contentType) == 0 || strings.ToLower(params["charset"]) == CHARSET_ISO2022JP {
-		// charset=ISO-2022-JP
-		mailbody, err = ioutil.ReadAll(transform.NewReader(body, japanese.ISO2022JP.NewDecoder()))
-	} else {
-		// encoding = 8bit or 7bit
-		mailbody, err = ioutil.ReadAll(body)
 	}
-	return mailbody, errors.Wrapf(err, "readPlainText:")
+	return strings.ToLower(strings.Trim(charset, " \t\"'"))
+}
+
+// readAllLimited reads r until EOF like io.ReadAll, but returns ErrBodyTooLarge
+// with the first max bytes when r has more than max bytes.
+func readAllLimited(r io.Reader, max int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return data, err
+	}
+	if int64(len(data)) > max {
+		return data[:max], ErrBodyTooLarge
+	}
+	return data, nil
+}
+
+// transferDecoder wraps body with the decoder for the Content-Transfer-Encoding.
+// 7bit, 8bit and binary are returned as is.
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case ENC_QUOTED_PRINTABLE:
+		return quotedprintable.NewReader(body)
+	case ENC_BASE64:
+		return newBase64Reader(body)
+	}
+	return body
+}
+
+// contentDecoder wraps body with the decompressor for the Content-Encoding
+// (gzip or deflate). Unknown content-encodings are returned as is.
+func contentDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		return &lazyReader{open: func() (io.Reader, error) { return gzip.NewReader(body) }}
+	case "deflate":
+		return &lazyReader{open: func() (io.Reader, error) { return zlib.NewReader(body) }}
+	}
+	return body
+}
+
+// lazyReader opens its reader on the first Read, since gzip.NewReader reads the stream header.
+type lazyReader struct {
+	open func() (io.Reader, error)
+	r    io.Reader
+	err  error
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.r == nil && l.err == nil {
+		l.r, l.err = l.open()
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.r.Read(p)
+}
+
+// base64Reader decodes a base64 stream written by careless mailers: whitespace
+// and stray bytes are skipped, missing or mid-stream "=" padding is accepted.
+// The data decoded around stray bytes is kept, and the error reporting them is
+// returned at the end instead of io.EOF.
+type base64Reader struct {
+	r       io.Reader
+	in      []byte
+	quad    [4]byte
+	nq      int    // quad の文字数
+	out     []byte // デコード済みで未読のデータ
+	invalid int    // 読み飛ばした不正なバイト数
+	err     error
+}
+
+func newBase64Reader(r io.Reader) *base64Reader {
+	return &base64Reader{r: r, in: make([]byte, 4096)}
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.out) == 0 && b.err == nil {
+		b.fill()
+	}
+	if len(b.out) > 0 {
+		n := copy(p, b.out)
+		b.out = b.out[n:]
+		return n, nil
+	}
+	return 0, b.err
+}
+
+func (b *base64Reader) fill() {
+	n, err := b.r.Read(b.in)
+	for _, c := range b.in[:n] {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '+', c == '/':
+			b.quad[b.nq] = c
+			b.nq++
+			if b.nq == 4 {
+				b.flush()
+			}
+		case c == '=':
+			// 途中の "=" はそこで区切る (パディング付きの塊を連結したもの)
+			b.flush()
+		case c == '\r', c == '\n', c == ' ', c == '\t':
+		default:
+			b.invalid++
+		}
+	}
+	if err == io.EOF {
+		b.flush()
+		if b.invalid > 0 {
+			err = &InvalidBase64Error{Skipped: b.invalid}
+		}
+	}
+	b.err = err
+}
+
+// An InvalidBase64Error is returned at the end of a base64 body with stray bytes.
+// The data decoded around them is returned with it.
+type InvalidBase64Error struct {
+	Skipped int
+}
+
+func (e *InvalidBase64Error) Error() string {
+	return "jmail: invalid base64: " + strconv.Itoa(e.Skipped) + " bytes skipped"
+}
+
+// isPartialText reports whether text decoded with err is still worth returning:
+// the text of a base64 body with stray bytes.
+func isPartialText(text []byte, err error) bool {
+	var b64err *InvalidBase64Error
+	return len(text) > 0 && errors.As(err, &b64err)
+}
+
+// flush decodes the characters in quad, which may be fewer than 4.
+func (b *base64Reader) flush() {
+	switch {
+	case b.nq == 1:
+		// 1 文字だけでは 1 バイトにもならない
+		b.invalid++
+	case b.nq > 1:
+		var dst [3]byte
+		n, _ := base64.RawStdEncoding.Decode(dst[:], b.quad[:b.nq])
+		b.out = append(b.out, dst[:n]...)
+	}
+	b.nq = 0
+}
+
+// SetAddressParser sets the parser used for the address headers of this message
+// instead of the package level AddressParser. nil restores the default.
+func (j *Jmessage) SetAddressParser(parser *mail.AddressParser) {
+	j.addressParser = parser
+}
+
+// SetDefaultCharset sets the charset assumed for the text parts of this message
+// without a charset parameter, instead of the package level DefaultCharset.
+// Empty guesses the charset with GuessCharset.
+func (j *Jmessage) SetDefaultCharset(charset string) {
+	j.defaultCharset = &charset
+}
+
+func (msg Jmessage) fallbackCharset() string {
+	if msg.defaultCharset != nil {
+		return *msg.defaultCharset
+	}
+	return DefaultCharset
+}
+
+func (j *Jmessage) parser() *mail.AddressParser {
+	if j.addressParser != nil {
+		return j.addressParser
+	}
+	return &AddressParser
 }
 
 func (j *Jmessage) GetFrom() ([]*mail.Address, error) {
-	list, err := AddressParser.ParseList(j.Header.Get("From"))
-	return list, err
+	return j.parseList(j.Header.Get("From"))
+}
+
+// GetFromAddr returns the first From address, or ErrNoAddress when there is none.
+func (j *Jmessage) GetFromAddr() (*mail.Address, error) {
+	list, err := j.getAddressList("From")
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNoAddress
+	}
+	return list[0], nil
 }
 
 func (j *Jmessage) GetTo() ([]*mail.Address, error) {
-	list, err := AddressParser.ParseList(j.Header.Get("To"))
-	return list, err
+	return j.parseList(j.Header.Get("To"))
+}
+
+// GetCc returns the Cc addresses. An absent header gives an empty list.
+func (j *Jmessage) GetCc() ([]*mail.Address, error) {
+	return j.getAddressList("Cc")
+}
+
+// GetBcc returns the Bcc addresses. An absent header gives an empty list.
+func (j *Jmessage) GetBcc() ([]*mail.Address, error) {
+	return j.getAddressList("Bcc")
+}
+
+// GetReplyTo returns the Reply-To addresses. An absent header gives an empty list.
+func (j *Jmessage) GetReplyTo() ([]*mail.Address, error) {
+	return j.getAddressList("Reply-To")
+}
+
+// GetSender returns the Sender address. An absent header gives a nil address and nil error.
+func (j *Jmessage) GetSender() (*mail.Address, error) {
+	header := j.Header.Get("Sender")
+	if header == "" {
+		return nil, nil
+	}
+	return j.parseAddress(header)
+}
+
+// GetHeaderAddresses parses any address header, such as X-Original-To or
+// Delivered-To, like GetCc. The addresses of a header repeated several times
+// are returned in order. An absent header gives an empty list.
+func (j *Jmessage) GetHeaderAddresses(key string) ([]*mail.Address, error) {
+	list := []*mail.Address{}
+	for _, value := range j.Header[textproto.CanonicalMIMEHeaderKey(key)] {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		addrs, err := j.parseList(value)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, addrs...)
+	}
+	return list, nil
+}
+
+func (j *Jmessage) getAddressList(key string) ([]*mail.Address, error) {
+	header := j.Header.Get(key)
+	if header == "" {
+		return []*mail.Address{}, nil
+	}
+	return j.parseList(header)
 }
 
 func (j *Jmessage) GetHeader(key string) string {
 	return j.Header.Get(key)
 }
+
+// GetHeaderRaw returns the first value of the header key exactly as received:
+// everything after the colon, including the whitespace following it and the
+// line folds, such as for canonicalizing headers to verify a signature. Only
+// the final line break is removed. Without the raw header block it is the
+// unfolded value of Header.
+func (j *Jmessage) GetHeaderRaw(key string) string {
+	if values := rawHeaderValues(j.rawHeaders, key); len(values) > 0 {
+		return values[0]
+	}
+	return j.Header.Get(key)
+}
+
+// GetHeaderValues returns all the values of the header key in order, such as the Received chain.
+func (j *Jmessage) GetHeaderValues(key string) []string {
+	values := j.Header[textproto.CanonicalMIMEHeaderKey(key)]
+	return append([]string(nil), values...)
+}
+
+// HeaderKeys returns the canonical header field names in the order they first appear in the message.
+func (j *Jmessage) HeaderKeys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(j.rawHeaders), "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			// 折り返し行
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			continue
+		}
+		key := textproto.CanonicalMIMEHeaderKey(strings.TrimRight(line[:i], " \t"))
+		if _, ok := j.Header[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	// 生ヘッダがない場合 (Jmessage を直接作った場合など) は名前順で補う
+	var rest []string
+	for key := range j.Header {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// RawSubject returns the Subject header as sent, with its encoded-words and
+// line folds, for debugging DecSubject. The whitespace after the colon is
+// removed. Without the raw header block it is the unfolded value of Header.
+func (j *Jmessage) RawSubject() string {
+	if values := rawHeaderValues(j.rawHeaders, "Subject"); len(values) > 0 {
+		return strings.TrimLeft(values[0], " \t")
+	}
+	return j.Header.Get("Subject")
+}
+
+// rawHeaderValues returns the values of the header key in the raw header block,
+// everything after the colon with the folds kept. The line break at the end of
+// each value is removed.
+func rawHeaderValues(raw []byte, key string) []string {
+	var values []string
+	var cur *strings.Builder
+	flush := func() {
+		if cur != nil {
+			values = append(values, strings.TrimRight(cur.String(), "\r\n"))
+			cur = nil
+		}
+	}
+	for _, line := range strings.SplitAfter(string(raw), "\n") {
+		if line != "" && (line[0] == ' ' || line[0] == '\t') {
+			// 折り返し行
+			if cur != nil {
+				cur.WriteString(line)
+			}
+			continue
+		}
+		flush()
+		i := strings.IndexByte(line, ':')
+		if i <= 0 || !strings.EqualFold(strings.TrimRight(line[:i], " \t"), key) {
+			continue
+		}
+		cur = &strings.Builder{}
+		cur.WriteString(line[i+1:])
+	}
+	flush()
+	return values
+}
@@ -1,16 +1,26 @@
 package jmail
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
-	// "strings"
+	"unicode/utf8"
 	// "fmt"
 	// "golang.org/x/text/encoding/japanese"
 	// "golang.org/x/text/transform"
 	// "io/ioutil"
-	// "mime"
+
+	"github.com/pkg/errors"
 )
 
 func TestDecSubject(t *testing.T) {
@@ -23,6 +33,21 @@ func TestDecSubject(t *testing.T) {
 		"【テスト環境】サイト更新が完了しました",
 		"【テスト環境】サイト更新が完了しました",
 		"【テスト環境】サイト更新が完了しました",
+		"Re: テスト環境  (no reply)",
+		"ホリネズミは、哺乳綱ネズミ目ホリネズミ科に属する哺乳類の総称である。北アメリカから中央アメリカにかけて分布し、地中にトンネルを掘って生活する。頬袋を持ち、食べ物を巣に運ぶ習性がある。ホリネズミは、哺乳綱ネズミ目ホリネズミ科に属する哺乳類の総称である。北アメリカから中央アメリカにかけて分布し、地中にトンネルを掘って生活する。頬袋を持ち、食べ物を巣に運ぶ習性がある。ホリネズミは、哺乳綱ネズミ目ホリネズ",
+		"【テスト環境】サイト更新が完了しました",
+		"【テスト環境】サイト更新が完了しました",
+		"=?x-unknown?B?Zm9v?= テスト",
+		"【テスト環境】サイト更新が完了しました",
+		"【テスト環境】サイト更新が完了しました",
+		"テスト 2015 年度 _report_",
+		"Gophers at Gophercon_2015 end テ ",
+		"【テスト環境】サイト更新が完了しました!テスト",
+		"Re:   URGENT  == ticket  #123",
+		"Re:  テストメール  [#123]",
+		"テストメール件名件名 (utf-8 + jis)",
+		"メールテスト end",
+		"【テスト環境】サイト更新が完了しました",
 	}
 	// outstr, _ := utf8_to_2022(chksubj)
 	// enc := mime.WordEncoder('b')
@@ -69,6 +94,17 @@ func TestDecBody(t *testing.T) {
 		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
 		"go go gopher!\r\n",
 		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。[image:\r\ntalks.png][image: doc.png]\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"ホリネズミ Caf〓 〓〓 end\r\n",
+		"ホリネズミ 〓 end\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
+		"サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。\r\n",
 	}
 
 	err := filepath.Walk(testemls,
@@ -105,6 +141,1423 @@ func TestDecBody(t *testing.T) {
 
 }
 
+func TestDecBodyHTML(t *testing.T) {
+	var msg *Jmessage
+	f, err := os.Open("./testbody/06test-html.eml")
+	if err != nil {
+		t.Fatalf("test: Failed open file: %v", err)
+	}
+	defer f.Close()
+	if msg, err = ReadMessage(f); err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	html, err := msg.DecBodyHTML()
+	if err != nil {
+		t.Fatalf("test: DecBodyHTML error: %v", err)
+	}
+	chkhtml := "<div dir=\"ltr\">サイトを更新した状態に保つことはセキュリティにとって重要です。"
+	if !strings.HasPrefix(string(html), chkhtml) || !strings.HasSuffix(string(html), "</div>\r\n") {
+		t.Errorf("test: DecBodyHTML error: (%s)", html)
+	}
+
+	g, err := os.Open("./testbody/05test-multipart.eml")
+	if err != nil {
+		t.Fatalf("test: Failed open file: %v", err)
+	}
+	defer g.Close()
+	if msg, err = ReadMessage(g); err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, err = msg.DecBodyHTML(); err != ErrNoHTMLPart {
+		t.Errorf("test: DecBodyHTML should return ErrNoHTMLPart: (%v)", err)
+	}
+}
+
+func TestDecBodyPartial(t *testing.T) {
+	header := `From: Gopher <from@example.com>
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+`
+	broken := `--BOUNDARY
+Content-Type: multipart/mixed
+
+AAAA
+`
+	text := `--BOUNDARY
+Content-Type: text/plain; charset="utf-8"
+
+go go gopher!
+`
+	end := "--BOUNDARY--\n"
+
+	msg, err := ReadMessage(strings.NewReader(header + broken + text + end))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	body, partErrs, err := msg.DecBodyPartial()
+	if err != nil {
+		t.Fatalf("test: DecBodyPartial error: %v", err)
+	}
+	if string(body) != "go go gopher!" {
+		t.Errorf("test: DecBodyPartial body error: (%s)", body)
+	}
+	if len(partErrs) != 1 {
+		t.Errorf("test: DecBodyPartial should report the broken part: (%v)", partErrs)
+	}
+
+	msg, err = ReadMessage(strings.NewReader(header + broken + broken + end))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	_, err = msg.DecBody()
+	if errs, ok := err.(PartErrors); !ok || len(errs) != 2 {
+		t.Errorf("test: DecBody should return PartErrors: (%v)", err)
+	}
+}
+
+func TestDecBodyNoText(t *testing.T) {
+	tests := []string{
+		"Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\n\n" +
+			"--BOUNDARY\nContent-Type: image/png\nContent-Transfer-Encoding: base64\n\niVBORw0KGgo=\n" +
+			"--BOUNDARY\nContent-Type: application/octet-stream\n\nAAAA\n" +
+			"--BOUNDARY--\n",
+		"Content-Type: application/pdf\nContent-Transfer-Encoding: base64\n\nJVBERi0=\n",
+	}
+	for _, eml := range tests {
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if body, err := msg.DecBody(); err != io.EOF {
+			t.Errorf("test: DecBody without text should return io.EOF: %q (%q, %v)", eml, body, err)
+		}
+		if _, partErrs, err := msg.DecBodyPartial(); err != io.EOF || len(partErrs) != 0 {
+			t.Errorf("test: DecBodyPartial without text error: %q (%v, %v)", eml, partErrs, err)
+		}
+	}
+}
+
+func TestGetCcBcc(t *testing.T) {
+	eml := `From: Gopher <from@example.com>
+To: Another Gopher <to@example.com>
+Cc: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <cc1@example.com>, cc2@example.com
+Subject: Gophers at Gophercon
+
+Message body
+`
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	cc, err := msg.GetCc()
+	if err != nil {
+		t.Fatalf("test: GetCc error: %v", err)
+	}
+	if len(cc) != 2 || cc[0].Name != "ホリネズミ" || cc[0].Address != "cc1@example.com" || cc[1].Address != "cc2@example.com" {
+		t.Errorf("test: GetCc error: (%v)", cc)
+	}
+	bcc, err := msg.GetBcc()
+	if err != nil || bcc == nil || len(bcc) != 0 {
+		t.Errorf("test: GetBcc should return an empty list: (%v, %v)", bcc, err)
+	}
+}
+
+func TestGetReplyTo(t *testing.T) {
+	eml := `From: Gopher <from@example.com>
+Reply-To: =?iso-2022-jp?B?GyRCJVslaiVNJTolXxsoQg==?= <reply@example.com>
+Subject: Gophers at Gophercon
+
+Message body
+`
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	replyTo, err := msg.GetReplyTo()
+	if err != nil {
+		t.Fatalf("test: GetReplyTo error: %v", err)
+	}
+	if len(replyTo) != 1 || replyTo[0].Name != "ホリネズミ" || replyTo[0].Address != "reply@example.com" {
+		t.Errorf("test: GetReplyTo error: (%v)", replyTo)
+	}
+
+	msg, err = ReadMessage(strings.NewReader("From: Gopher <from@example.com>\n\nMessage body\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if replyTo, err = msg.GetReplyTo(); err != nil || len(replyTo) != 0 {
+		t.Errorf("test: GetReplyTo should return an empty list: (%v, %v)", replyTo, err)
+	}
+}
+
+func TestDecSubjectErr(t *testing.T) {
+	tests := []struct {
+		eml  string
+		subj string
+		err  error
+	}{
+		{"From: Gopher <from@example.com>\n\nMessage body\n", "", ErrNoSubject},
+		{"From: Gopher <from@example.com>\nSubject:\n\nMessage body\n", "", nil},
+		{"From: Gopher <from@example.com>\nSubject: =?UTF-8?B?44OG44K544OI?=\n\nMessage body\n", "テスト", nil},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader(tt.eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		subj, err := msg.DecSubjectErr()
+		if subj != tt.subj || err != tt.err {
+			t.Errorf("test: DecSubjectErr error: %q (%s, %v)", tt.eml, subj, err)
+		}
+	}
+}
+
+func TestDecHeader(t *testing.T) {
+	eml := `From: Gopher <from@example.com>
+Subject: Gophers at Gophercon
+Organization: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= Inc.
+X-Mailer: =?EUC-JP?B?pdul6qXNpbql3w==?=
+
+Message body
+`
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	chkheader := map[string]string{
+		"Organization": "ホリネズミ Inc.",
+		"X-Mailer":     "ホリネズミ",
+		"X-Empty":      "",
+	}
+	for key, want := range chkheader {
+		if got := msg.DecHeader(key); got != want {
+			t.Errorf("test: DecHeader error: %s (%s)", key, got)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		eml       string
+		mediatype string
+		charset   string
+	}{
+		{"From: Gopher <from@example.com>\n\nMessage body\n", "text/plain", "us-ascii"},
+		{"Content-Type: Text/Plain; charset=\"ISO-2022-JP\"\n\nMessage body\n", "text/plain", "ISO-2022-JP"},
+		{"Content-Type: multipart/mixed; boundary=BOUNDARY\n\n--BOUNDARY--\n", "multipart/mixed", ""},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader(tt.eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		mediatype, params, err := msg.ContentType()
+		if err != nil {
+			t.Errorf("test: ContentType error: %q (%v)", tt.eml, err)
+			continue
+		}
+		if mediatype != tt.mediatype || params["charset"] != tt.charset {
+			t.Errorf("test: ContentType error: %q (%s, %v)", tt.eml, mediatype, params)
+		}
+	}
+}
+
+func TestDecBodyReader(t *testing.T) {
+	for _, eml := range []string{"./testbody/01test-iso2022jp.eml", "./testbody/05test-multipart.eml", "./testbody/06test-html.eml", "./testbody/17test-iso2022jp-base64.eml"} {
+		want, err := openTestMessage(t, eml).DecBody()
+		if err != nil {
+			t.Fatalf("test: DecBody error: %s (%v)", eml, err)
+		}
+
+		r, err := openTestMessage(t, eml).DecBodyReader()
+		if err != nil {
+			t.Errorf("test: DecBodyReader error: %s (%v)", eml, err)
+			continue
+		}
+		body, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(body) != string(want) {
+			t.Errorf("test: DecBodyReader error: %s (%s, %v)", eml, body, err)
+		}
+	}
+}
+
+func TestDecBodyReaderBroken(t *testing.T) {
+	eml := "Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+		"--BOUNDARY\r\nContent-Type: multipart/alternative\r\n\r\nbroken\r\n" +
+		"--BOUNDARY\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nreal body\r\n" +
+		"--BOUNDARY--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	want, err := msg.Clone().DecBody()
+	if err != nil || string(want) != "real body" {
+		t.Fatalf("test: DecBody error: %q (%v)", want, err)
+	}
+	r, err := msg.DecBodyReader()
+	if err != nil {
+		t.Fatalf("test: DecBodyReader should skip the broken section: (%v)", err)
+	}
+	body, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(body) != string(want) {
+		t.Errorf("test: DecBodyReader error: %q (%v)", body, err)
+	}
+
+	msg, err = ReadMessage(strings.NewReader("Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+		"--BOUNDARY\r\nContent-Type: multipart/alternative\r\n\r\nbroken\r\n" +
+		"--BOUNDARY--\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, err := msg.DecBodyReader(); err == nil || err == io.EOF {
+		t.Errorf("test: DecBodyReader should return PartErrors: (%v)", err)
+	}
+}
+
+func TestDecBodyHTMLBroken(t *testing.T) {
+	eml := "Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+		"--BOUNDARY\r\nContent-Type: multipart/alternative\r\n\r\nbroken\r\n" +
+		"--BOUNDARY\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>real body</p>\r\n" +
+		"--BOUNDARY--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	_, want, err := msg.Clone().DecBodies()
+	if err != nil || string(want) != "<p>real body</p>" {
+		t.Fatalf("test: DecBodies error: %q (%v)", want, err)
+	}
+	if html, err := msg.DecBodyHTML(); err != nil || string(html) != string(want) {
+		t.Errorf("test: DecBodyHTML should skip the broken section: %q (%v)", html, err)
+	}
+}
+
+// openTestMessage reads the message from the test eml file.
+func openTestMessage(t *testing.T, eml string) *Jmessage {
+	f, err := os.Open(eml)
+	if err != nil {
+		t.Fatalf("test: Failed open file: %s (%v)", eml, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	msg, err := ReadMessage(f)
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %s (%v)", eml, err)
+	}
+	return msg
+}
+
+func TestMaxBodySize(t *testing.T) {
+	defer func(max int64) { MaxBodySize = max }(MaxBodySize)
+	MaxBodySize = 8
+
+	msg, err := ReadMessage(strings.NewReader("Content-Type: text/plain; charset=utf-8\n\ngo go gopher!\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	body, err := msg.DecBody()
+	if errors.Cause(err) != ErrBodyTooLarge || string(body) != "go go go" {
+		t.Errorf("test: DecBody should return ErrBodyTooLarge: (%s, %v)", body, err)
+	}
+
+	msg, err = ReadMessage(strings.NewReader("Content-Type: text/plain; charset=utf-8\n\ngopher\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, err = msg.DecBody(); err != nil || string(body) != "gopher\n" {
+		t.Errorf("test: DecBody error: (%s, %v)", body, err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	nested := func(depth int) string {
+		var eml strings.Builder
+		eml.WriteString("From: Gopher <from@example.com>\n")
+		for i := 0; i < depth; i++ {
+			eml.WriteString("Content-Type: multipart/mixed; boundary=B" + strconv.Itoa(i) + "\n\n--B" + strconv.Itoa(i) + "\n")
+		}
+		eml.WriteString("Content-Type: text/plain; charset=utf-8\n\ngo go gopher!\n")
+		for i := depth - 1; i >= 0; i-- {
+			eml.WriteString("--B" + strconv.Itoa(i) + "--\n")
+		}
+		return eml.String()
+	}
+
+	msg, err := ReadMessage(strings.NewReader(nested(MaxDepth)))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "go go gopher!" {
+		t.Errorf("test: DecBody error: (%s, %v)", body, err)
+	}
+
+	msg, err = ReadMessage(strings.NewReader(nested(MaxDepth + 1)))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, err := msg.DecBody(); err != ErrTooDeep {
+		t.Errorf("test: DecBody should return ErrTooDeep: (%v)", err)
+	}
+}
+
+func TestDecBodies(t *testing.T) {
+	plain, html, err := openTestMessage(t, "./testbody/06test-html.eml").DecBodies()
+	if err != nil {
+		t.Fatalf("test: DecBodies error: %v", err)
+	}
+	if !strings.HasPrefix(string(plain), "サイトを更新した状態に保つこと") {
+		t.Errorf("test: DecBodies plain error: (%s)", plain)
+	}
+	if !strings.HasPrefix(string(html), "<div dir=\"ltr\">サイトを更新した状態に保つこと") {
+		t.Errorf("test: DecBodies html error: (%s)", html)
+	}
+
+	plain, html, err = openTestMessage(t, "./testbody/05test-multipart.eml").DecBodies()
+	if err != nil || string(plain) != "go go gopher!\r\n" || html != nil {
+		t.Errorf("test: DecBodies error: (%s, %s, %v)", plain, html, err)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	data, err := os.ReadFile("./testsubj/01test-iso2022jpb.eml")
+	if err != nil {
+		t.Fatalf("test: Failed read file: %v", err)
+	}
+	msg, err := ParseMessage(data)
+	if err != nil {
+		t.Fatalf("test: ParseMessage error: %v", err)
+	}
+	if msg.DecSubject() != "【テスト環境】サイト更新が完了しました" {
+		t.Errorf("test: ParseMessage subject error: (%s)", msg.DecSubject())
+	}
+}
+
+func TestRawHeaders(t *testing.T) {
+	for _, eml := range []string{"./testbody/01test-iso2022jp.eml", "./testbody/06test-html.eml", "./testsubj/06test-folded.eml"} {
+		data, err := os.ReadFile(eml)
+		if err != nil {
+			t.Fatalf("test: Failed read file: %s (%v)", eml, err)
+		}
+		msg := openTestMessage(t, eml)
+		raw := msg.RawHeaders()
+		if !strings.HasPrefix(string(data), string(raw)) || !strings.HasPrefix(string(data[len(raw):]), "\r\n") && !strings.HasPrefix(string(data[len(raw):]), "\n") {
+			t.Errorf("test: RawHeaders error: %s (%q)", eml, raw)
+		}
+		if _, err := msg.DecBody(); err != nil {
+			t.Errorf("test: DecBody after RawHeaders error: %s (%v)", eml, err)
+		}
+	}
+}
+
+func TestHeaderKeys(t *testing.T) {
+	eml := "X-Mailer: gopher\r\nreceived: from a\r\nReceived: from b\r\nSubject: go\r\n run\r\nFrom: Gopher <from@example.com>\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	var m Message = msg
+	keys := m.HeaderKeys()
+	chkkeys := []string{"X-Mailer", "Received", "Subject", "From"}
+	if strings.Join(keys, ",") != strings.Join(chkkeys, ",") {
+		t.Errorf("test: HeaderKeys error: (%v)", keys)
+	}
+}
+
+func TestGetHeaderValues(t *testing.T) {
+	eml := "Received: from a by b\r\nFrom: Gopher <from@example.com>\r\nReceived: from c\r\n by d\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	received := msg.GetHeaderValues("received")
+	if len(received) != 2 || received[0] != "from a by b" || received[1] != "from c by d" {
+		t.Errorf("test: GetHeaderValues error: (%q)", received)
+	}
+	if values := msg.GetHeaderValues("X-None"); len(values) != 0 {
+		t.Errorf("test: GetHeaderValues error: (%q)", values)
+	}
+}
+
+func TestSetAddressParser(t *testing.T) {
+	eml := "From: =?x-gopher?B?Z29waGVy?= <from@example.com>\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	// 未知の charset の表示名はデコードせずに返す
+	from, err := msg.GetFrom()
+	if err != nil || len(from) != 1 || from[0].Name != "=?x-gopher?B?Z29waGVy?=" {
+		t.Errorf("test: GetFrom with an unknown charset error: (%v, %v)", from, err)
+	}
+
+	msg.SetAddressParser(&mail.AddressParser{
+		WordDecoder: &mime.WordDecoder{
+			CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+				return input, nil
+			},
+		},
+	})
+	from, err = msg.GetFrom()
+	if err != nil || len(from) != 1 || from[0].Name != "gopher" {
+		t.Errorf("test: GetFrom with SetAddressParser error: (%v, %v)", from, err)
+	}
+}
+
+func TestGetSender(t *testing.T) {
+	eml := "From: Gopher <from@example.com>\r\nSender: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <list@example.jp>\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	sender, err := msg.GetSender()
+	if err != nil || sender == nil || sender.Name != "ホリネズミ" || sender.Address != "list@example.jp" {
+		t.Errorf("test: GetSender error: (%v, %v)", sender, err)
+	}
+
+	msg, err = ReadMessage(strings.NewReader("From: Gopher <from@example.com>\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if sender, err = msg.GetSender(); sender != nil || err != nil {
+		t.Errorf("test: GetSender should return nil: (%v, %v)", sender, err)
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	text := "サイトを更新した状態に保つことはセキュリティにとって重要です。"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(text))
+	zw.Close()
+
+	tests := []struct {
+		encoding string
+		body     string
+		want     string
+	}{
+		{"gzip", base64.StdEncoding.EncodeToString(gz.Bytes()), text},
+		{"x-unknown", base64.StdEncoding.EncodeToString([]byte(text)), text},
+	}
+	for _, tt := range tests {
+		eml := "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\nContent-Encoding: " + tt.encoding + "\r\n\r\n" + tt.body + "\r\n"
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBody()
+		if err != nil || string(body) != tt.want {
+			t.Errorf("test: DecBody error: %s (%s, %v)", tt.encoding, body, err)
+		}
+	}
+}
+
+func TestPartCount(t *testing.T) {
+	tests := []struct {
+		eml       string
+		multipart bool
+		count     int
+	}{
+		{"./testbody/00test.eml", false, 0},
+		{"./testbody/05test-multipart.eml", true, 3},
+		{"./testbody/06test-html.eml", true, 3},
+	}
+	for _, tt := range tests {
+		msg := openTestMessage(t, tt.eml)
+		if msg.IsMultipart() != tt.multipart {
+			t.Errorf("test: IsMultipart error: %s", tt.eml)
+		}
+		if count, err := msg.PartCount(); err != nil || count != tt.count {
+			t.Errorf("test: PartCount error: %s (%d, %v)", tt.eml, count, err)
+		}
+	}
+}
+
+func TestDefaultCharset(t *testing.T) {
+	text := "サイトを更新した状態に保つことはセキュリティにとって重要です。"
+	jis := "\x1b$B%;%-%e%j%F%#\x1b(B"
+	tests := []struct {
+		charset string
+		header  string
+		body    string
+		want    string
+	}{
+		{"", "", text, text},
+		{"", "Content-Type: text/plain\r\n", text, text},
+		{"", "Content-Type: text/plain\r\nContent-Transfer-Encoding: quoted-printable\r\n", "=E3=82=B5=E3=82=A4=E3=83=88", "サイト"},
+		{"", "Content-Type: text/plain; charset=iso-2022-jp\r\n", jis, "セキュリティ"},
+		{CHARSET_ISO2022JP, "", jis, "セキュリティ"},
+		{CHARSET_ISO2022JP, "Content-Type: text/plain; charset=utf-8\r\n", text, text},
+	}
+	defer func(c string) { DefaultCharset = c }(DefaultCharset)
+	for _, tt := range tests {
+		DefaultCharset = tt.charset
+		msg, err := ReadMessage(strings.NewReader("Subject: test\r\n" + tt.header + "\r\n" + tt.body + "\r\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBody()
+		if err != nil || strings.TrimSpace(string(body)) != tt.want {
+			t.Errorf("test: DecBody error: %q %q (%s, %v)", tt.charset, tt.header, body, err)
+		}
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain subject", "plain subject"},
+		{"=?UTF-8?B?44OG44K544OI?= =?UTF-8?B?44Oh44O844Or?=", "テストメール"},
+		{"Re: =?UTF-8?Q?=E3=83=86=E3=82=B9=E3=83=88?= mail", "Re: テスト mail"},
+		{"=?UTF-8?Q?a_b?=", "a b"},
+		{"=?UTF-8?B?44OG44K544OI", "テスト"},
+		{"=?UTF-8?B?44OG44K544O", "テス\xe3\x83"},
+		{"=?x-unknown?B?44OG?=", "=?x-unknown?B?44OG?="},
+		{"=?UTF-8?B?44OG44K5\r\n 44OI?=", "テスト"},
+	}
+	for _, tt := range tests {
+		if got := decodeHeader(tt.value); got != tt.want {
+			t.Errorf("test: decodeHeader error: %q (%q)", tt.value, got)
+		}
+	}
+}
+
+func TestTransferCharset(t *testing.T) {
+	text := "セキュリティ"
+	charsets := []struct {
+		charset string
+		data    string
+	}{
+		{"iso-2022-jp", "\x1b$B%;%-%e%j%F%#\x1b(B"},
+		{"utf-8", text},
+		{"", text},
+	}
+	for _, encoding := range []string{"7bit", "8bit", "base64", "quoted-printable"} {
+		for _, cs := range charsets {
+			body := cs.data
+			switch encoding {
+			case "base64":
+				body = base64.StdEncoding.EncodeToString([]byte(cs.data))
+			case "quoted-printable":
+				var buf bytes.Buffer
+				w := quotedprintable.NewWriter(&buf)
+				w.Write([]byte(cs.data))
+				w.Close()
+				body = buf.String()
+			}
+			contentType := "text/plain"
+			if cs.charset != "" {
+				contentType += "; charset=" + cs.charset
+			}
+			eml := "Content-Type: " + contentType + "\r\nContent-Transfer-Encoding: " + encoding + "\r\n\r\n" + body + "\r\n"
+			msg, err := ReadMessage(strings.NewReader(eml))
+			if err != nil {
+				t.Fatalf("test: ReadMessage error: %v", err)
+			}
+			decoded, err := msg.DecBody()
+			if err != nil || strings.TrimSpace(string(decoded)) != text {
+				t.Errorf("test: DecBody error: %s %q (%q, %v)", encoding, cs.charset, decoded, err)
+			}
+		}
+	}
+}
+
+func TestDecBodyGuessCharset(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"\x1b$B%[%j%M%:%_\x1b(B", "ホリネズミ"},
+		{"\xa5\xdb\xa5\xea\xa5\xcd\xa5\xba\xa5\xdf", "ホリネズミ"},
+		{"\x83\x7a\x83\x8a\x83\x6c\x83\x59\x83\x7e", "ホリネズミ"},
+		{"ホリネズミ", "ホリネズミ"},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Content-Type: text/plain\r\n\r\n" + tt.body))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBody()
+		if err != nil || string(body) != tt.want {
+			t.Errorf("test: DecBody error: %q (%s, %v)", tt.body, body, err)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	eml := "Subject: =?x-unknown?B?Z29waGVy?= test\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+		"--BOUNDARY\r\nContent-Type: multipart/alternative\r\n\r\nbroken\r\n" +
+		"--BOUNDARY\r\nContent-Type: text/plain; charset=utf-8\r\n\r\ngo go gopher!\r\n" +
+		"--BOUNDARY--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if w := msg.Warnings(); len(w) != 0 {
+		t.Errorf("test: Warnings error: %v", w)
+	}
+	if subj := msg.DecSubject(); subj != "=?x-unknown?B?Z29waGVy?= test" {
+		t.Errorf("test: DecSubject error: %s", subj)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "go go gopher!" {
+		t.Errorf("test: DecBody error: %q (%v)", body, err)
+	}
+	w := msg.Warnings()
+	if len(w) != 2 || !strings.Contains(w[0].Error(), "x-unknown") {
+		t.Errorf("test: Warnings error: %v", w)
+	}
+	// 何度呼んでも警告は増えない
+	msg.DecSubject()
+	msg.DecBody()
+	if w := msg.Warnings(); len(w) != 2 {
+		t.Errorf("test: Warnings duplicated error: %v", w)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg.DecSubject()
+		}()
+	}
+	wg.Wait()
+	if w := msg.Warnings(); len(w) != 2 {
+		t.Errorf("test: Warnings concurrent error: %v", w)
+	}
+
+	msg = openTestMessage(t, "./testsubj/02test-utf8b.eml")
+	msg.DecSubject()
+	if w := msg.Warnings(); len(w) != 0 {
+		t.Errorf("test: Warnings error: %v", w)
+	}
+}
+
+func TestBase64Garbage(t *testing.T) {
+	text := "サイトを更新した状態に保つことはセキュリティにとって重要です。"
+	enc := base64.StdEncoding.EncodeToString([]byte(text))
+	tests := []struct {
+		body    string
+		invalid bool
+	}{
+		{enc[:20] + "\r\n " + enc[20:40] + "\t\r\n" + enc[40:], false},
+		{base64.StdEncoding.EncodeToString([]byte(text[:10])) + base64.StdEncoding.EncodeToString([]byte(text[10:])), false},
+		{enc[:20] + "!*" + enc[20:40] + "\x00" + enc[40:], true},
+	}
+	for _, tt := range tests {
+		eml := "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n" + tt.body + "\r\n"
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBody()
+		if string(body) != text || (err != nil) != tt.invalid {
+			t.Errorf("test: DecBody error: %q (%s, %v)", tt.body, body, err)
+		}
+
+		// multipart の中でもデコードできた分は失われない
+		eml = "Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+			"--BOUNDARY\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n" + tt.body + "\r\n" +
+			"--BOUNDARY--\r\n"
+		msg, err = ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err = msg.DecBody()
+		if string(body) != text || (err != nil) != tt.invalid {
+			t.Errorf("test: DecBody multipart error: %q (%s, %v)", tt.body, body, err)
+		}
+		plain, _, err := msg.DecBodies()
+		if string(plain) != text || err != nil {
+			t.Errorf("test: DecBodies multipart error: %q (%s, %v)", tt.body, plain, err)
+		}
+	}
+}
+
+func TestGetFromAddr(t *testing.T) {
+	msg := openTestMessage(t, "./testsubj/00test.eml")
+	from, err := msg.GetFromAddr()
+	if err != nil || from.Address != "from@example.com" {
+		t.Errorf("test: GetFromAddr error: (%v, %v)", from, err)
+	}
+
+	msg, err = ReadMessage(strings.NewReader("To: to@example.com\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if from, err := msg.GetFromAddr(); err != ErrNoAddress {
+		t.Errorf("test: GetFromAddr should return ErrNoAddress: (%v, %v)", from, err)
+	}
+}
+
+func TestDecBodyWithCharset(t *testing.T) {
+	tests := []struct {
+		eml     string
+		charset string
+	}{
+		{"./testbody/00test.eml", "utf-8"},
+		{"./testbody/05test-multipart.eml", "iso-2022-jp"},
+		{"./testbody/07test-sjis-base64.eml", "shift_jis"},
+	}
+	for _, tt := range tests {
+		msg := openTestMessage(t, tt.eml)
+		if _, charset, err := msg.DecBodyWithCharset(); err != nil || charset != tt.charset {
+			t.Errorf("test: DecBodyWithCharset error: %s (%s, %v)", tt.eml, charset, err)
+		}
+	}
+
+	msg, err := ReadMessage(strings.NewReader("Content-Type: text/plain\r\n\r\n\xa5\xdb\xa5\xea\xa5\xcd\xa5\xba\xa5\xdf"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, charset, err := msg.DecBodyWithCharset(); err != nil || charset != CHARSET_EUCJP || string(body) != "ホリネズミ" {
+		t.Errorf("test: DecBodyWithCharset guess error: (%s, %s, %v)", body, charset, err)
+	}
+}
+
+func TestPermissiveQP(t *testing.T) {
+	tests := []struct {
+		body       string
+		permissive string
+	}{
+		{"=E3=83=86=E3=82=B9=E3=83=88 =\r\nmail", "テスト mail"},
+		{"a=b, c=d", "a=b, c=d"},
+		{"=E3=83=86 x=1", "=E3=83=86 x=1"},
+	}
+	defer func(p bool) { PermissiveQP = p }(PermissiveQP)
+	for _, tt := range tests {
+		for _, permissive := range []bool{false, true} {
+			PermissiveQP = permissive
+			eml := "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 7bit\r\n\r\n" + tt.body
+			msg, err := ReadMessage(strings.NewReader(eml))
+			if err != nil {
+				t.Fatalf("test: ReadMessage error: %v", err)
+			}
+			want := tt.body
+			if permissive {
+				want = tt.permissive
+			}
+			if body, err := msg.DecBody(); err != nil || string(body) != want {
+				t.Errorf("test: DecBody error: %v %q (%q, %v)", permissive, tt.body, body, err)
+			}
+		}
+	}
+}
+
+func TestDecBodyString(t *testing.T) {
+	tests := []struct {
+		header string
+		body   string
+	}{
+		{"Content-Type: text/plain; charset=iso-2022-jp", "\x1b$B%[%j\xff\xfe%M\x1b(B\x80 gopher"},
+		{"Content-Type: text/plain; charset=x-unknown", "\xa5\xdb\xa5\xea gopher"},
+		{"Content-Type: text/plain; charset=utf-8", "\xe3\x83 gopher"},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader(tt.header + "\r\n\r\n" + tt.body))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBodyString()
+		if err != nil || !utf8.ValidString(body) || !strings.HasSuffix(body, " gopher") || !strings.Contains(body, "�") {
+			t.Errorf("test: DecBodyString error: %s (%q, %v)", tt.header, body, err)
+		}
+	}
+}
+
+func TestDecBodySigned(t *testing.T) {
+	// 本文が text でない署名付きメールでは署名を本文として返さない
+	eml := "Content-Type: multipart/signed; protocol=\"application/pgp-signature\"; boundary=\"SIGNED\"\r\n\r\n" +
+		"--SIGNED\r\nContent-Type: image/png\r\nContent-Transfer-Encoding: base64\r\n\r\niVBORw0KGgo=\r\n" +
+		"--SIGNED\r\nContent-Type: text/plain\r\n\r\n-----BEGIN PGP SIGNATURE-----\r\n" +
+		"--SIGNED--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, err := msg.DecBody(); strings.Contains(string(body), "SIGNATURE") {
+		t.Errorf("test: DecBody returned the signature: (%s, %v)", body, err)
+	}
+
+	eml = "Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\"; boundary=\"ENC\"\r\n\r\n" +
+		"--ENC\r\nContent-Type: application/pgp-encrypted\r\n\r\nVersion: 1\r\n" +
+		"--ENC\r\nContent-Type: application/octet-stream\r\n\r\n-----BEGIN PGP MESSAGE-----\r\n" +
+		"--ENC--\r\n"
+	for _, decode := range []func(*Jmessage) error{
+		func(msg *Jmessage) error { _, err := msg.DecBody(); return err },
+		func(msg *Jmessage) error { _, err := msg.DecBodyReader(); return err },
+		func(msg *Jmessage) error { _, err := msg.DecBodyHTML(); return err },
+		func(msg *Jmessage) error { _, _, err := msg.DecBodies(); return err },
+	} {
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if err := decode(msg); err != ErrEncrypted {
+			t.Errorf("test: ErrEncrypted error: (%v)", err)
+		}
+	}
+}
+
+func TestRawISO2022JPHeaders(t *testing.T) {
+	eml := "Subject: Re: \x1b$B%[%j%M%:%_\x1b(B test\r\n" +
+		"From: \x1b$B%[%j%M%:%_\x1b(B <gopher@example.jp>\r\n\r\nMessage body\r\n"
+	defer func(raw bool) { RawISO2022JPHeaders = raw }(RawISO2022JPHeaders)
+	for _, raw := range []bool{false, true} {
+		RawISO2022JPHeaders = raw
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		subj := "Re: \x1b$B%[%j%M%:%_\x1b(B test"
+		if raw {
+			subj = "Re: ホリネズミ test"
+		}
+		if got := msg.DecSubject(); got != subj {
+			t.Errorf("test: DecSubject error: %v (%q)", raw, got)
+		}
+		if !raw {
+			continue
+		}
+		if from, err := msg.GetFrom(); err != nil || len(from) != 1 || from[0].Name != "ホリネズミ" {
+			t.Errorf("test: GetFrom error: (%v, %v)", from, err)
+		}
+	}
+}
+
+func TestMaxHeader(t *testing.T) {
+	many := strings.Repeat("X-Spam: spam\r\n", 3000)
+	long := "X-Spam: " + strings.Repeat("spam ", 60<<10) + "\r\n"
+	for _, header := range []string{many, long} {
+		_, err := ReadMessage(strings.NewReader("Subject: test\r\n" + header + "\r\nMessage body\r\n"))
+		if err != ErrHeaderTooLarge {
+			t.Errorf("test: ReadMessage should fail with ErrHeaderTooLarge: %d (%v)", len(header), err)
+		}
+	}
+
+	// 本文の大きさは関係ない
+	msg, err := ReadMessage(strings.NewReader("Subject: test\r\n\r\n" + many))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != many {
+		t.Errorf("test: DecBody error: (%d, %v)", len(body), err)
+	}
+}
+
+func TestRawSubject(t *testing.T) {
+	msg := openTestMessage(t, "./testsubj/03test-iso2022jpq.eml")
+	want := "=?ISO-2022-JP?Q??=\r\n =?ISO-2022-JP?Q?=1B$B!Z%F%9%H4D6-![%5%$%H99=3F7$,40N;$7$^$7$=3F=1B(B?="
+	if raw := msg.RawSubject(); raw != want {
+		t.Errorf("test: RawSubject error: %q", raw)
+	}
+	if msg.DecSubject() != "【テスト環境】サイト更新が完了しました" {
+		t.Errorf("test: DecSubject error: %s", msg.DecSubject())
+	}
+
+	msg = &Jmessage{Message: &mail.Message{Header: mail.Header{"Subject": {"=?UTF-8?B?44OG44K544OI?="}}}}
+	if raw := msg.RawSubject(); raw != "=?UTF-8?B?44OG44K544OI?=" {
+		t.Errorf("test: RawSubject without raw headers error: %q", raw)
+	}
+}
+
+func TestMissingBoundary(t *testing.T) {
+	eml := "Content-Type: multipart/mixed\r\n\r\n--BOUNDARY\r\nContent-Type: text/plain\r\n\r\ngo go gopher!\r\n--BOUNDARY--\r\n"
+	for _, decode := range []func(*Jmessage) error{
+		func(msg *Jmessage) error { _, err := msg.DecBody(); return err },
+		func(msg *Jmessage) error { _, err := msg.DecBodyReader(); return err },
+		func(msg *Jmessage) error { _, err := msg.DecBodyHTML(); return err },
+		func(msg *Jmessage) error { _, err := msg.PartCount(); return err },
+		func(msg *Jmessage) error { _, err := msg.Attachments(); return err },
+	} {
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if err := decode(msg); err != ErrMissingBoundary {
+			t.Errorf("test: ErrMissingBoundary error: (%v)", err)
+		}
+	}
+}
+
+func TestBodyReaderWithMeta(t *testing.T) {
+	for _, tt := range []struct {
+		eml, mediatype, charset string
+	}{
+		{"./testbody/01test-iso2022jp.eml", "text/plain", "iso-2022-jp"},
+		{"./testbody/05test-multipart.eml", "text/plain", "iso-2022-jp"},
+	} {
+		want, err := openTestMessage(t, tt.eml).DecBody()
+		if err != nil {
+			t.Fatalf("test: DecBody error: %s (%v)", tt.eml, err)
+		}
+
+		r, mediatype, charset, err := openTestMessage(t, tt.eml).BodyReaderWithMeta()
+		if err != nil {
+			t.Errorf("test: BodyReaderWithMeta error: %s (%v)", tt.eml, err)
+			continue
+		}
+		body, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(body) != string(want) {
+			t.Errorf("test: BodyReaderWithMeta error: %s (%s, %v)", tt.eml, body, err)
+		}
+		if mediatype != tt.mediatype || !strings.EqualFold(charset, tt.charset) {
+			t.Errorf("test: BodyReaderWithMeta error: %s (%s, %s)", tt.eml, mediatype, charset)
+		}
+	}
+}
+
+func TestDecBodyNormalized(t *testing.T) {
+	eml := "Content-Type: text/plain; charset=utf-8\r\n\r\none\r\ntwo\rthree\nfour\r\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	body, err := msg.DecBodyNormalized()
+	if err != nil || string(body) != "one\ntwo\nthree\nfour\n\n" {
+		t.Errorf("test: DecBodyNormalized error: %q (%v)", body, err)
+	}
+}
+
+func TestSetDefaultCharset(t *testing.T) {
+	text := "ホリネズミ"
+	sjis := "\x83\x7a\x83\x8a\x83\x6c\x83\x59\x83\x7e"
+	defer func(c string) { DefaultCharset = c }(DefaultCharset)
+	DefaultCharset = CHARSET_ISO2022JP
+	for _, tt := range []struct {
+		header string
+		want   string
+	}{
+		{"", text},
+		{"Content-Type: text/plain\r\n", text},
+		{"Content-Type: multipart/mixed; boundary=X\r\n", text},
+	} {
+		body := sjis
+		if strings.Contains(tt.header, "multipart") {
+			body = "--X\r\nContent-Type: text/plain\r\n\r\n" + sjis + "\r\n--X--"
+		}
+		msg, err := ReadMessage(strings.NewReader("Subject: test\r\n" + tt.header + "\r\n" + body + "\r\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		msg.SetDefaultCharset("shift_jis")
+		got, charset, err := msg.DecBodyWithCharset()
+		if err != nil || strings.TrimSpace(string(got)) != tt.want || charset != "shift_jis" {
+			t.Errorf("test: SetDefaultCharset error: %q (%s, %s, %v)", tt.header, got, charset, err)
+		}
+	}
+}
+
+func TestCharsetParam(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{`text/plain`, ""},
+		{`Text/Plain; CharSet="ISO-2022-JP"`, "iso-2022-jp"},
+		{`text/plain; charset = Shift_JIS`, "shift_jis"},
+		{`text/plain; charset="ISO-2022-JP "`, "iso-2022-jp"},
+		{`text/plain; charset='iso-2022-jp'`, "iso-2022-jp"},
+		{`text/plain; format=flowed; charset="\"UTF-8\""`, "utf-8"},
+		{`text/plain; CHARSET="iso-2022-jp`, "iso-2022-jp"},
+		{`text/plain; charset=euc-jp; charset=utf-8`, "euc-jp"},
+	}
+	for _, tt := range tests {
+		if got := charsetParam(tt.contentType); got != tt.want {
+			t.Errorf("test: charsetParam error: %s (%s)", tt.contentType, got)
+		}
+	}
+
+	msg, err := ReadMessage(strings.NewReader("Content-Type: Text/Plain; CharSet=\"ISO-2022-JP \r\n\r\n\x1b$B%F%9%H\x1b(B\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, charset, err := msg.DecBodyWithCharset(); err != nil || string(body) != "テスト\r\n" || charset != "iso-2022-jp" {
+		t.Errorf("test: DecBodyWithCharset quoted charset error: (%q, %s, %v)", body, charset, err)
+	}
+	msg, err = ReadMessage(strings.NewReader("Content-Type: Multipart/Mixed; Boundary=X\r\n\r\n--X\r\nContent-Type: TEXT/PLAIN; CharSet=\"ISO-2022-JP\"\r\n\r\n\x1b$B%F%9%H\x1b(B\r\n--X--\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "テスト" {
+		t.Errorf("test: DecBody upper-case media type error: (%q, %v)", body, err)
+	}
+}
+
+func TestDecBodyRereadable(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/06test-html.eml")
+	first, err := msg.DecBody()
+	if err != nil || len(first) == 0 {
+		t.Fatalf("test: DecBody error: (%v)", err)
+	}
+	if html, err := msg.DecBodyHTML(); err != nil || len(html) == 0 {
+		t.Errorf("test: DecBodyHTML after DecBody error: (%v)", err)
+	}
+	if _, err := msg.Attachments(); err != nil {
+		t.Errorf("test: Attachments after DecBody error: (%v)", err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != string(first) {
+		t.Errorf("test: DecBody twice error: (%s, %v)", body, err)
+	}
+	r, err := msg.DecBodyReader()
+	if err != nil {
+		t.Fatalf("test: DecBodyReader error: (%v)", err)
+	}
+	if body, err := io.ReadAll(r); err != nil || string(body) != string(first) {
+		t.Errorf("test: DecBodyReader after DecBody error: (%s, %v)", body, err)
+	}
+
+	msg = openTestMessage(t, "./testbody/01test-iso2022jp.eml")
+	if r, err = msg.DecBodyReader(); err != nil {
+		t.Fatalf("test: DecBodyReader error: (%v)", err)
+	}
+	io.ReadAll(r)
+	if _, err := msg.DecBody(); errors.Cause(err) != ErrBodyStreamed {
+		t.Errorf("test: DecBody after DecBodyReader error: (%v)", err)
+	}
+}
+
+func TestSubstitutionWarning(t *testing.T) {
+	eml := "Subject: =?UTF-8?B?44OG/w==?=\r\nContent-Type: text/plain; charset=iso-2022-jp\r\n\r\n\x1b$B%F%9%H\x1b(B \x1b$B\x7f\x7f\x1b(B\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	msg.DecSubject()
+	if _, err := msg.DecBody(); err != nil {
+		t.Fatalf("test: DecBody error: %v", err)
+	}
+	var where []string
+	for _, w := range msg.Warnings() {
+		if se, ok := w.(*SubstitutionError); ok {
+			where = append(where, se.Where)
+		}
+	}
+	if strings.Join(where, ",") != "Subject,body" {
+		t.Errorf("test: SubstitutionError error: %v", msg.Warnings())
+	}
+
+	msg = openTestMessage(t, "./testbody/01test-iso2022jp.eml")
+	msg.DecSubject()
+	msg.DecBody()
+	if w := msg.Warnings(); len(w) != 0 {
+		t.Errorf("test: SubstitutionError clean message error: %v", w)
+	}
+}
+
+func TestPreferredText(t *testing.T) {
+	tests := []struct {
+		eml        string
+		preferHTML bool
+		mediatype  string
+	}{
+		{"./testbody/06test-html.eml", true, MEDIATYPE_TEXT_HTML},
+		{"./testbody/06test-html.eml", false, MEDIATYPE_TEXT_PLAIN},
+		{"./testbody/05test-multipart.eml", true, MEDIATYPE_TEXT_PLAIN},
+		{"./testbody/01test-iso2022jp.eml", false, MEDIATYPE_TEXT_PLAIN},
+	}
+	for _, tt := range tests {
+		body, mediatype, err := openTestMessage(t, tt.eml).PreferredText(tt.preferHTML)
+		if err != nil || mediatype != tt.mediatype || len(body) == 0 {
+			t.Errorf("test: PreferredText error: %s %v (%s, %v)", tt.eml, tt.preferHTML, mediatype, err)
+		}
+	}
+
+	msg, err := ReadMessage(strings.NewReader("Content-Type: multipart/alternative; boundary=X\r\n\r\n--X\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>html</p>\r\n--X--\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, mediatype, err := msg.PreferredText(false); err != nil || mediatype != MEDIATYPE_TEXT_HTML || string(body) != "<p>html</p>" {
+		t.Errorf("test: PreferredText fallback error: (%s, %s, %v)", body, mediatype, err)
+	}
+
+	msg, err = ReadMessage(strings.NewReader("Content-Type: multipart/mixed; boundary=X\r\n\r\n--X\r\nContent-Type: image/png\r\n\r\nxx\r\n--X--\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if _, _, err := msg.PreferredText(true); err != io.EOF {
+		t.Errorf("test: PreferredText without text error: (%v)", err)
+	}
+}
+
+func TestGetHeaderAddresses(t *testing.T) {
+	eml := "Delivered-To: gopher@example.com\r\n" +
+		"Delivered-To: =?ISO-2022-JP?B?GyRCJVslaiVNJTolXxsoQg==?= <alias@example.com>\r\n" +
+		"X-Original-To: a@example.com, b@example.com\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	addrs, err := msg.GetHeaderAddresses("delivered-to")
+	if err != nil || len(addrs) != 2 || addrs[0].Address != "gopher@example.com" || addrs[1].Name != "ホリネズミ" {
+		t.Errorf("test: GetHeaderAddresses error: (%v, %v)", addrs, err)
+	}
+	if addrs, err = msg.GetHeaderAddresses("X-Original-To"); err != nil || len(addrs) != 2 {
+		t.Errorf("test: GetHeaderAddresses error: (%v, %v)", addrs, err)
+	}
+	if addrs, err = msg.GetHeaderAddresses("X-Envelope-To"); err != nil || addrs == nil || len(addrs) != 0 {
+		t.Errorf("test: GetHeaderAddresses absent error: (%v, %v)", addrs, err)
+	}
+}
+
+func TestMaxPartSize(t *testing.T) {
+	defer func(n int64) { MaxPartSize = n }(MaxPartSize)
+	MaxPartSize = 16
+	eml := "Content-Type: multipart/mixed; boundary=X\r\n\r\n" +
+		"--X\r\nContent-Type: image/png; name=a.png\r\n\r\n0123456789\r\n" +
+		"--X\r\nContent-Type: text/plain\r\n\r\n0123456789abcdefghij\r\n" +
+		"--X\r\nContent-Type: image/png; name=b.png\r\n\r\n0123456789abcdefghij\r\n--X--\r\n"
+	for _, tt := range []struct {
+		decode func(*Jmessage) error
+		index  int
+	}{
+		{func(msg *Jmessage) error { _, err := msg.DecBody(); return err }, 2},
+		{func(msg *Jmessage) error { _, _, err := msg.DecBodies(); return err }, 2},
+		{func(msg *Jmessage) error { _, err := msg.Attachments(); return err }, 3},
+	} {
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		err = tt.decode(msg)
+		var tooLarge *PartTooLargeError
+		if errors.Cause(err) != ErrPartTooLarge || !errors.As(err, &tooLarge) || tooLarge.Index != tt.index {
+			t.Errorf("test: ErrPartTooLarge error: %d (%v)", tt.index, err)
+		}
+	}
+
+	MaxPartSize = 20
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "0123456789abcdefghij" {
+		t.Errorf("test: MaxPartSize exact size error: (%s, %v)", body, err)
+	}
+}
+
+func TestAttachedTextBody(t *testing.T) {
+	eml := "./testbody/16test-mixed-attached-text.eml"
+	text := "サイトを更新した状態に保つことはセキュリティにとって重要です。それはまた、あなたとあなたの読者にとってインターネットをより安全な場所にすることでもあります。"
+	plain, html, err := openTestMessage(t, eml).DecBodies()
+	if err != nil || string(plain) != text+"\r\n" || string(html) != "<p>"+text+"</p>" {
+		t.Errorf("test: DecBodies attached text error: (%s, %s, %v)", plain, html, err)
+	}
+	r, err := openTestMessage(t, eml).DecBodyReader()
+	if err != nil {
+		t.Fatalf("test: DecBodyReader error: (%v)", err)
+	}
+	if body, err := io.ReadAll(r); err != nil || string(body) != text+"\r\n" {
+		t.Errorf("test: DecBodyReader attached text error: (%s, %v)", body, err)
+	}
+
+	// 添付されたテキストしかなければそれを返す
+	only := "Content-Type: multipart/mixed; boundary=X\r\n\r\n--X\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Disposition: attachment; filename=readme.txt\r\n\r\nreadme\r\n--X--\r\n"
+	for _, decode := range []func(*Jmessage) ([]byte, error){
+		(*Jmessage).DecBody,
+		func(msg *Jmessage) ([]byte, error) { plain, _, err := msg.DecBodies(); return plain, err },
+		func(msg *Jmessage) ([]byte, error) {
+			r, err := msg.DecBodyReader()
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+	} {
+		msg, err := ReadMessage(strings.NewReader(only))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if body, err := decode(msg); err != nil || string(body) != "readme" {
+			t.Errorf("test: attached text fallback error: (%s, %v)", body, err)
+		}
+	}
+}
+
+func BenchmarkDecSubject(b *testing.B) {
+	for _, eml := range []string{"./testsubj/00test.eml", "./testsubj/01test-iso2022jpb.eml", "./testsubj/06test-folded.eml", "./testsubj/17test-mixed-charset.eml"} {
+		f, err := os.Open(eml)
+		if err != nil {
+			b.Fatalf("test: Failed open file: %s (%v)", eml, err)
+		}
+		msg, err := ReadMessage(f)
+		f.Close()
+		if err != nil {
+			b.Fatalf("test: ReadMessage error: %s (%v)", eml, err)
+		}
+		b.Run(filepath.Base(eml), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				msg.DecSubject()
+			}
+		})
+	}
+}
+
+func TestReadMessageRaw(t *testing.T) {
+	data, err := os.ReadFile("./testbody/05test-multipart.eml")
+	if err != nil {
+		t.Fatalf("test: Failed read file: %v", err)
+	}
+	msg, err := ReadMessageRaw(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("test: ReadMessageRaw error: %v", err)
+	}
+	if !bytes.Equal(msg.Raw(), data) {
+		t.Errorf("test: Raw error: (%d bytes)", len(msg.Raw()))
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "go go gopher!\r\n" {
+		t.Errorf("test: DecBody after ReadMessageRaw error: (%s, %v)", body, err)
+	}
+	if subj := msg.DecSubject(); subj != "go run gopher" {
+		t.Errorf("test: DecSubject after ReadMessageRaw error: %s", subj)
+	}
+	if raw := openTestMessage(t, "./testbody/05test-multipart.eml").Raw(); raw != nil {
+		t.Errorf("test: Raw without ReadMessageRaw error: (%d bytes)", len(raw))
+	}
+}
+
+func TestSubjectIsJapanese(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    bool
+	}{
+		{"Gophers at Gophercon", false},
+		{"=?UTF-8?B?44OG44K544OI?=", true},
+		{"=?ISO-2022-JP?B?GyRCNEE7ehsoQg==?=", true},
+		{"Re: =?UTF-8?B?772x772y?= (ｱｲ)", true},
+		{"=?UTF-8?B?w6nDqA==?= cafe", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Subject: " + tt.subject + "\r\n\r\nMessage body\r\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if got := msg.SubjectIsJapanese(); got != tt.want {
+			t.Errorf("test: SubjectIsJapanese error: %s (%v)", tt.subject, got)
+		}
+	}
+}
+
+func TestAssumeISO2022JP(t *testing.T) {
+	jis := "\x1b$B%F%9%H\x1b(B"
+	latin1 := "caf\xe9"
+	defer func(b bool) { AssumeISO2022JP = b }(AssumeISO2022JP)
+	tests := []struct {
+		assume bool
+		header string
+		body   string
+		want   string
+	}{
+		{true, "", jis, "テスト"},
+		{false, "", jis, jis},
+		{false, "", latin1, latin1},
+		{false, "Content-Type: text/plain\r\n", jis, jis},
+		{false, "Content-Type: text/plain; charset=iso-2022-jp\r\n", jis, "テスト"},
+	}
+	for _, tt := range tests {
+		AssumeISO2022JP = tt.assume
+		msg, err := ReadMessage(strings.NewReader("Subject: test\r\n" + tt.header + "\r\n" + tt.body))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBody()
+		if err != nil || string(body) != tt.want {
+			t.Errorf("test: AssumeISO2022JP error: %v %q (%q, %v)", tt.assume, tt.header, body, err)
+		}
+	}
+}
+
+func TestBinaryTransferEncoding(t *testing.T) {
+	jis := "\x1b$B%F%9%H\x1b(B"
+	defer func(b bool) { PermissiveQP = b }(PermissiveQP)
+	PermissiveQP = true
+	tests := []struct {
+		header string
+		body   string
+		want   string
+	}{
+		{"Content-Type: text/plain\r\nContent-Transfer-Encoding: binary\r\n", jis, jis},
+		{"Content-Type: text/plain\r\nContent-Transfer-Encoding: Binary\r\n", "a=3Db=3Dc=3D\x00\xff", "a=3Db=3Dc=3D\x00\xff"},
+		{"Content-Type: text/plain; charset=iso-2022-jp\r\nContent-Transfer-Encoding: binary\r\n", jis, "テスト"},
+		{"Content-Type: text/plain\r\nContent-Transfer-Encoding: 7bit\r\n", jis, "テスト"},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Subject: test\r\n" + tt.header + "\r\n" + tt.body))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBody()
+		if err != nil || string(body) != tt.want {
+			t.Errorf("test: binary error: %q (%q, %v)", tt.header, body, err)
+		}
+	}
+
+	eml := "Content-Type: multipart/mixed; boundary=X\r\n\r\n--X\r\nContent-Type: text/plain\r\n\r\nbody\r\n" +
+		"--X\r\nContent-Type: application/octet-stream; name=a.bin\r\nContent-Transfer-Encoding: binary\r\n\r\n\x00\x01=41\xff\r\n--X--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	atts, err := msg.Attachments()
+	if err != nil || len(atts) != 1 || string(atts[0].Data) != "\x00\x01=41\xff" {
+		t.Errorf("test: binary attachment error: (%v, %v)", atts, err)
+	}
+}
+
+func TestDecSubjectCase(t *testing.T) {
+	tests := []struct {
+		subj string
+		want string
+	}{
+		{"=?ISO-2022-JP?B?GyRCJUYlOSVIGyhC?=", "テスト"},
+		{"=?iso-2022-jp?b?GyRCJUYlOSVIGyhC?=", "テスト"},
+		{"=?Iso-2022-Jp?B?GyRCJUYlOSVIGyhC?=", "テスト"},
+		{"=?Utf-8?q?=E3=83=86=E3=82=B9=E3=83=88?=", "テスト"},
+		{"=?UTF-8?Q?=E3=83=86=E3=82=B9=E3=83=88?=", "テスト"},
+		{"=?uTf-8?B?44OG44K544OI?=", "テスト"},
+		{"=?SHIFT_JIS?B?g2WDWINn?=", "テスト"},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Subject: " + tt.subj + "\n\nMessage body\n"))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if got := msg.DecSubject(); got != tt.want {
+			t.Errorf("test: DecSubject case error: %s (%s)", tt.subj, got)
+		}
+	}
+}
+
+func TestGetHeaderRaw(t *testing.T) {
+	eml := "From: Gopher <from@example.com>\r\n" +
+		"DKIM-Signature: v=1; a=rsa-sha256;\r\n\td=example.com; s=sel;\r\n b=AAAA\r\n" +
+		"Subject:  test\r\n\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	chkheader := map[string]string{
+		"dkim-signature": " v=1; a=rsa-sha256;\r\n\td=example.com; s=sel;\r\n b=AAAA",
+		"Subject":        "  test",
+		"X-Empty":        "",
+	}
+	for key, want := range chkheader {
+		if got := msg.GetHeaderRaw(key); got != want {
+			t.Errorf("test: GetHeaderRaw error: %s (%q)", key, got)
+		}
+	}
+
+	msg = &Jmessage{Message: &mail.Message{Header: mail.Header{"Subject": {"test"}}}}
+	if got := msg.GetHeaderRaw("Subject"); got != "test" {
+		t.Errorf("test: GetHeaderRaw without raw header error: (%q)", got)
+	}
+}
+
 // // UTF-8 から ISO-2022-JP
 // func utf8_to_2022(str string) (string, error) {
 //   iostr := strings.NewReader(str)
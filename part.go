@@ -0,0 +1,128 @@
+package jmail
+
+import (
+	"io"
+	"mime/multipart"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A Part is a leaf part of a message visited by WalkParts.
+// The message itself is the only part of a message that is not multipart.
+type Part struct {
+	Header    mail.Header
+	MediaType string
+	Params    map[string]string
+	// Depth is the multipart nesting depth of the part, 0 for the message itself.
+	Depth int
+	// ID is the part number as in IMAP, such as "1.2" for the second part of the
+	// first part. The body of a message that is not multipart is "1".
+	ID string
+
+	body io.Reader
+	// fallback はメッセージの SetDefaultCharset (または DefaultCharset)
+	fallback string
+}
+
+// Reader returns the content of the part with its Content-Transfer-Encoding decoded.
+// It can be read only during the callback of WalkParts.
+func (p *Part) Reader() io.Reader {
+	return transferDecoder(p.Header.Get("Content-Transfer-Encoding"), p.body)
+}
+
+// Bytes reads the content of the part with its Content-Transfer-Encoding decoded,
+// up to MaxBodySize bytes.
+func (p *Part) Bytes() ([]byte, error) {
+	return readAllLimited(p.Reader(), MaxBodySize)
+}
+
+// Text reads the content of a text part decoded to UTF-8. A part without a
+// charset parameter uses the charset of SetDefaultCharset, like DecBody.
+func (p *Part) Text() ([]byte, error) {
+	text, _, err := readText(map[string][]string(p.Header), p.body, p.fallback)
+	return text, err
+}
+
+// WalkParts calls fn for each leaf part of the message in depth-first order,
+// descending into multipart parts. An error returned by fn stops the walk and
+// is returned by WalkParts.
+func (msg Jmessage) WalkParts(fn func(part *Part) error) error {
+	fallback := msg.fallbackCharset()
+	return walkParts(msg.Header, msg.body(), 0, "", func(part *Part) error {
+		part.fallback = fallback
+		return fn(part)
+	})
+}
+
+// PartSizes returns the size of each leaf part with its Content-Transfer-Encoding
+// decoded, keyed by Part.ID. The parts are decoded as a stream and discarded.
+func (msg Jmessage) PartSizes() (map[string]int64, error) {
+	sizes := map[string]int64{}
+	err := msg.WalkParts(func(part *Part) error {
+		n, err := io.Copy(io.Discard, part.Reader())
+		sizes[part.ID] = n
+		return errors.Wrapf(err, "PartSizes: %s:", part.ID)
+	})
+	return sizes, err
+}
+
+// CalendarParts returns all the text/calendar parts (iCalendar invitations)
+// decoded to UTF-8, in order.
+func (msg Jmessage) CalendarParts() ([][]byte, error) {
+	var cals [][]byte
+	err := msg.WalkParts(func(part *Part) error {
+		if part.MediaType != MEDIATYPE_TEXT_CALENDAR {
+			return nil
+		}
+		cal, err := part.Text()
+		if err != nil {
+			return errors.Wrapf(err, "CalendarParts: %s:", part.ID)
+		}
+		cals = append(cals, cal)
+		return nil
+	})
+	return cals, err
+}
+
+func walkParts(header mail.Header, body io.Reader, depth int, id string, fn func(part *Part) error) error {
+	mediatype, params, err := parseContentType(header)
+	if err != nil {
+		return errors.Wrapf(err, "walkParts:")
+	}
+	if !strings.HasPrefix(mediatype, MEDIATYPE_MULTI) {
+		if id == "" {
+			id = "1"
+		}
+		return fn(&Part{Header: header, MediaType: mediatype, Params: params, Depth: depth, ID: id, body: body})
+	}
+	if params["boundary"] == "" {
+		return ErrMissingBoundary
+	}
+	if depth >= MaxDepth {
+		return ErrTooDeep
+	}
+	mr := multipart.NewReader(body, params["boundary"])
+	for n := 1; ; n++ {
+		p, err := mr.NextPart()
+		if lp, ok := body.(*limitedPart); ok && lp.exceeded {
+			return &PartTooLargeError{Index: lp.index}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "walkParts: NextPart:")
+		}
+		childID := strconv.Itoa(n)
+		if id != "" {
+			childID = id + "." + childID
+		}
+		lp := &limitedPart{Part: p, index: n}
+		if err := walkParts(mail.Header(p.Header), lp, depth+1, childID, fn); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package jmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestWalkParts(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/05test-multipart.eml")
+	var types []string
+	err := msg.WalkParts(func(part *Part) error {
+		types = append(types, part.MediaType)
+		if part.Depth != 1 {
+			t.Errorf("test: Part depth error: %s (%d)", part.MediaType, part.Depth)
+		}
+		if part.MediaType == MEDIATYPE_TEXT_PLAIN {
+			text, err := part.Text()
+			if err != nil || !strings.Contains(string(text), "gopher") {
+				t.Errorf("test: Part Text error: %s (%v)", text, err)
+			}
+		} else {
+			data, err := part.Bytes()
+			if err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+				t.Errorf("test: Part Bytes error: %s (%v)", part.MediaType, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("test: WalkParts error: %v", err)
+	}
+	if strings.Join(types, ",") != "text/plain,image/png,image/png" {
+		t.Errorf("test: WalkParts error: %s", types)
+	}
+
+	stop := errors.New("stop")
+	msg = openTestMessage(t, "./testbody/05test-multipart.eml")
+	count := 0
+	err = msg.WalkParts(func(part *Part) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("test: WalkParts stop error: %d (%v)", count, err)
+	}
+
+	msg = openTestMessage(t, "./testbody/00test.eml")
+	err = msg.WalkParts(func(part *Part) error {
+		types = append(types[:0], part.MediaType)
+		return nil
+	})
+	if err != nil || len(types) != 1 || types[0] != MEDIATYPE_TEXT_PLAIN {
+		t.Errorf("test: WalkParts error: %s (%v)", types, err)
+	}
+}
+
+func TestPartSizes(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/00test.eml")
+	sizes, err := msg.PartSizes()
+	if err != nil || len(sizes) != 1 || sizes["1"] != int64(len("Message body\r\n")) {
+		t.Errorf("test: PartSizes error: (%v, %v)", sizes, err)
+	}
+
+	msg = openTestMessage(t, "./testbody/06test-html.eml")
+	sizes, err = msg.PartSizes()
+	if err != nil {
+		t.Fatalf("test: PartSizes error: %v", err)
+	}
+	// PartSizes のあとも同じメッセージをデコードできる
+	atts, err := msg.Attachments()
+	if err != nil || len(atts) != 2 {
+		t.Fatalf("test: Attachments error: %v", err)
+	}
+	ids := make([]string, 0, len(sizes))
+	for id := range sizes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "1.1,1.2,2,3" {
+		t.Errorf("test: PartSizes ids error: %s", ids)
+	}
+	if sizes["2"] != int64(len(atts[0].Data)) || sizes["3"] != int64(len(atts[1].Data)) {
+		t.Errorf("test: PartSizes error: (%v)", sizes)
+	}
+}
+
+func TestCalendarParts(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nBEGIN:VEVENT\r\nSUMMARY:ホリネズミ定例会\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	eml := "Content-Type: multipart/mixed; boundary=\"MIX\"\r\n\r\n" +
+		"--MIX\r\nContent-Type: multipart/alternative; boundary=\"ALT\"\r\n\r\n" +
+		"--ALT\r\nContent-Type: text/plain; charset=utf-8\r\n\r\ninvitation\r\n" +
+		"--ALT\r\nContent-Type: text/calendar; charset=iso-2022-jp; method=REQUEST\r\n\r\n" +
+		"BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nBEGIN:VEVENT\r\nSUMMARY:\x1b$B%[%j%M%:%_DjNc2q\x1b(B\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n\r\n" +
+		"--ALT--\r\n" +
+		"--MIX\r\nContent-Type: text/calendar; charset=utf-8; name=invite.ics\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(ics)) + "\r\n" +
+		"--MIX--\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	cals, err := msg.CalendarParts()
+	if err != nil || len(cals) != 2 {
+		t.Fatalf("test: CalendarParts error: (%d, %v)", len(cals), err)
+	}
+	for i, cal := range cals {
+		if string(cal) != ics {
+			t.Errorf("test: CalendarParts error: %d (%q)", i, cal)
+		}
+	}
+}
+
+func TestPartTextDefaultCharset(t *testing.T) {
+	msg, err := ReadMessage(strings.NewReader("Content-Type: text/calendar\r\n\r\n\x83\x65\x83\x58\x83\x67"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	msg.SetDefaultCharset("shift_jis")
+	cals, err := msg.CalendarParts()
+	if err != nil || len(cals) != 1 || string(cals[0]) != "テスト" {
+		t.Errorf("test: Part.Text SetDefaultCharset error: (%q, %v)", cals, err)
+	}
+}
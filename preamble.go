@@ -0,0 +1,93 @@
+package jmail
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Preamble returns the raw text of a multipart message before its first
+// boundary, which mail clients don't show. It is nil for an empty preamble and
+// for a message that is not multipart. Like DecBody, it reads the message body.
+func (msg Jmessage) Preamble() ([]byte, error) {
+	preamble, _, err := msg.outOfBand()
+	return preamble, err
+}
+
+// Epilogue returns the raw text of a multipart message after its closing
+// boundary. It is nil for an empty epilogue, a missing closing boundary and
+// for a message that is not multipart.
+func (msg Jmessage) Epilogue() ([]byte, error) {
+	_, epilogue, err := msg.outOfBand()
+	return epilogue, err
+}
+
+// outOfBand splits the preamble and the epilogue out of the raw multipart body.
+func (msg Jmessage) outOfBand() (preamble, epilogue []byte, err error) {
+	mediatype, params, err := parseContentType(msg.Header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(mediatype, MEDIATYPE_MULTI) {
+		return nil, nil, nil
+	}
+	if params["boundary"] == "" {
+		return nil, nil, ErrMissingBoundary
+	}
+	body, err := readAllLimited(msg.body(), MaxBodySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	delimiter := []byte("--" + params["boundary"])
+	closing := []byte("--" + params["boundary"] + "--")
+
+	first := -1
+	for start := 0; start < len(body); {
+		line := nextLine(body, start)
+		if isDelimiterLine(body[start:line], delimiter) {
+			first = start
+			break
+		}
+		start = line
+	}
+	if first < 0 {
+		// 区切りが 1 つもない
+		return nonEmpty(body), nil, nil
+	}
+	// 区切りの前の改行は区切りの一部
+	preamble = bytes.TrimSuffix(body[:first], []byte("\n"))
+	preamble = bytes.TrimSuffix(preamble, []byte("\r"))
+
+	for start := first; start < len(body); {
+		line := nextLine(body, start)
+		if text := bytes.TrimRight(body[start:line], " \t\r\n"); bytes.Equal(text, closing) {
+			return nonEmpty(preamble), nonEmpty(body[line:]), nil
+		}
+		start = line
+	}
+	return nonEmpty(preamble), nil, nil
+}
+
+// nextLine returns the start of the line after the one at start.
+func nextLine(body []byte, start int) int {
+	if i := bytes.IndexByte(body[start:], '\n'); i >= 0 {
+		return start + i + 1
+	}
+	return len(body)
+}
+
+// isDelimiterLine reports whether line is a boundary delimiter line, "--" and
+// the boundary followed by optional whitespace (or "--" for the closing one).
+func isDelimiterLine(line, delimiter []byte) bool {
+	if !bytes.HasPrefix(line, delimiter) {
+		return false
+	}
+	rest := bytes.TrimPrefix(line[len(delimiter):], []byte("--"))
+	return len(bytes.TrimRight(rest, " \t\r\n")) == 0
+}
+
+func nonEmpty(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
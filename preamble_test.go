@@ -0,0 +1,41 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreambleEpilogue(t *testing.T) {
+	tests := []struct {
+		body     string
+		preamble string
+		epilogue string
+	}{
+		{"--X\r\n\r\npart\r\n--X--\r\n", "", ""},
+		{"This is a multi-part message in MIME format.\r\n\r\n--X\r\n\r\npart\r\n--X--\r\nhidden\r\ncontent\r\n",
+			"This is a multi-part message in MIME format.\r\n", "hidden\r\ncontent\r\n"},
+		{"pre\n--X \n\npart\n--X-- \nafter", "pre", "after"},
+		{"pre\r\n--XY\r\n--X\r\n\r\npart\r\n", "pre\r\n--XY", ""},
+		{"no boundary at all\r\n", "no boundary at all\r\n", ""},
+	}
+	for _, tt := range tests {
+		eml := "Content-Type: multipart/mixed; boundary=X\r\n\r\n" + tt.body
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		preamble, err := msg.Preamble()
+		if err != nil || string(preamble) != tt.preamble {
+			t.Errorf("test: Preamble error: %q (%q, %v)", tt.body, preamble, err)
+		}
+		epilogue, err := msg.Epilogue()
+		if err != nil || string(epilogue) != tt.epilogue {
+			t.Errorf("test: Epilogue error: %q (%q, %v)", tt.body, epilogue, err)
+		}
+	}
+
+	msg := openTestMessage(t, "./testbody/01test-iso2022jp.eml")
+	if preamble, err := msg.Preamble(); preamble != nil || err != nil {
+		t.Errorf("test: Preamble not multipart error: (%q, %v)", preamble, err)
+	}
+}
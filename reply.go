@@ -0,0 +1,58 @@
+package jmail
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// ReplyDelimiters are the lines DecBodyTopPost takes as the start of the quoted
+// history: the line and everything after it are stripped.
+var ReplyDelimiters = []*regexp.Regexp{
+	regexp.MustCompile(`^-{2,}\s*Original Message\s*-{2,}$`),
+	regexp.MustCompile(`^-{2,}\s*(元のメッセージ|原文|Forwarded message)\s*-{2,}$`),
+	regexp.MustCompile(`^(From|差出人)\s*[:：]`),
+	regexp.MustCompile(`^On .+ wrote:$`),
+	regexp.MustCompile(`^\d{4}年\d{1,2}月\d{1,2}日.*[:：]$`),
+	regexp.MustCompile(`(さんは書きました|のメール)\s*[:：]$`),
+}
+
+// DecBodyTopPost is like DecBody, but returns only the new content of a reply:
+// the lines quoted with ">" are dropped, and the body is cut at the first line
+// matching one of ReplyDelimiters. It is a heuristic.
+func (msg Jmessage) DecBodyTopPost() ([]byte, error) {
+	body, err := msg.DecBody()
+	if err != nil {
+		return body, err
+	}
+	return stripQuoted(body), nil
+}
+
+// stripQuoted drops the quoted lines and the history after a reply delimiter.
+func stripQuoted(body []byte) []byte {
+	var out []byte
+	for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+		text := bytes.TrimSpace(line)
+		if bytes.HasPrefix(text, []byte(">")) {
+			continue
+		}
+		if isReplyDelimiter(text) {
+			break
+		}
+		out = append(out, line...)
+	}
+	// 区切りの前の空行を取り除く
+	end := len(bytes.TrimRight(out, " \t\r\n"))
+	if i := bytes.IndexByte(out[end:], '\n'); i >= 0 {
+		end += i + 1
+	}
+	return out[:end]
+}
+
+func isReplyDelimiter(line []byte) bool {
+	for _, re := range ReplyDelimiters {
+		if re.Match(line) {
+			return true
+		}
+	}
+	return false
+}
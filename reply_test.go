@@ -0,0 +1,31 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecBodyTopPost(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"Thanks!\r\n", "Thanks!\r\n"},
+		{"Thanks!\r\n\r\nOn Wed, 16 Sep 2015 at 05:32, Gopher <from@example.com> wrote:\r\n> Hello\r\n", "Thanks!\r\n"},
+		{"See below.\r\n> quoted\r\nmy answer\r\n>> older\r\n", "See below.\r\nmy answer\r\n"},
+		{"了解しました。\r\n\r\n-----Original Message-----\r\nFrom: Gopher\r\n", "了解しました。\r\n"},
+		{"了解しました。\r\n\r\n差出人: Gopher <from@example.com>\r\n送信日時: 2015年9月16日 5:32\r\n", "了解しました。\r\n"},
+		{"了解です\r\n\r\n2015年9月16日(水) 5:32 Gopher <from@example.com>:\r\n> テスト\r\n", "了解です\r\n"},
+		{"OK\r\n\r\nGopher さんは書きました:\r\n", "OK\r\n"},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader("Content-Type: text/plain; charset=utf-8\r\n\r\n" + tt.body))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		body, err := msg.DecBodyTopPost()
+		if err != nil || string(body) != tt.want {
+			t.Errorf("test: DecBodyTopPost error: %q (%q, %v)", tt.body, body, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package jmail
+
+import "strings"
+
+// MessageID returns the Message-ID without the angle brackets.
+// An absent or malformed header gives "".
+func (j *Jmessage) MessageID() string {
+	ids := parseMessageIDs(j.Header.Get("Message-ID"))
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
+// InReplyTo returns the message-ids of the In-Reply-To header.
+func (j *Jmessage) InReplyTo() []string {
+	return parseMessageIDs(j.Header.Get("In-Reply-To"))
+}
+
+// References returns the message-ids of the References header in order.
+func (j *Jmessage) References() []string {
+	return parseMessageIDs(j.Header.Get("References"))
+}
+
+// parseMessageIDs returns the message-ids in value without the angle brackets.
+// Malformed entries and comments are skipped.
+func parseMessageIDs(value string) []string {
+	ids := []string{}
+	value = unfold(value)
+	if !strings.Contains(value, "<") {
+		// 山括弧なしで並べる古いメーラー
+		for _, field := range strings.Fields(value) {
+			if isMessageID(field) {
+				ids = append(ids, field)
+			}
+		}
+		return ids
+	}
+	for {
+		start := strings.Index(value, "<")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(value[start:], ">")
+		if end < 0 {
+			break
+		}
+		id := strings.TrimSpace(value[start+1 : start+end])
+		if isMessageID(id) {
+			ids = append(ids, id)
+		}
+		value = value[start+end+1:]
+	}
+	return ids
+}
+
+// isMessageID reports whether id looks like left@right.
+func isMessageID(id string) bool {
+	at := strings.LastIndex(id, "@")
+	return at > 0 && at < len(id)-1 && !strings.ContainsAny(id, "<>()\" \t")
+}
@@ -0,0 +1,42 @@
+package jmail
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestThreadHeaders(t *testing.T) {
+	eml := "Message-ID: <abc.123@example.com>\r\n" +
+		"In-Reply-To: <parent@example.com> (Gopher's message of Wed, 16 Sep 2015)\r\n" +
+		"References: <root@example.com>\r\n <broken> <>\r\n\t<parent@example.com><child@example.com>\r\n" +
+		"\r\nMessage body\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if id := msg.MessageID(); id != "abc.123@example.com" {
+		t.Errorf("test: MessageID error: %q", id)
+	}
+	if ids := msg.InReplyTo(); !reflect.DeepEqual(ids, []string{"parent@example.com"}) {
+		t.Errorf("test: InReplyTo error: %q", ids)
+	}
+	want := []string{"root@example.com", "parent@example.com", "child@example.com"}
+	if ids := msg.References(); !reflect.DeepEqual(ids, want) {
+		t.Errorf("test: References error: %q", ids)
+	}
+
+	msg, err = ReadMessage(strings.NewReader("References: root@example.com parent@example.com\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	if id := msg.MessageID(); id != "" {
+		t.Errorf("test: MessageID error: %q", id)
+	}
+	if ids := msg.InReplyTo(); len(ids) != 0 {
+		t.Errorf("test: InReplyTo error: %q", ids)
+	}
+	if ids := msg.References(); !reflect.DeepEqual(ids, []string{"root@example.com", "parent@example.com"}) {
+		t.Errorf("test: References error: %q", ids)
+	}
+}
@@ -0,0 +1,59 @@
+package jmail
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoTNEF is returned by TNEFData when the message has no TNEF (winmail.dat) part.
+var ErrNoTNEF = errors.New("jmail: no TNEF part")
+
+// errTNEFFound は最初の TNEF パートで WalkParts を止めるために使う
+var errTNEFFound = errors.New("jmail: TNEF part found")
+
+// isTNEF reports whether part is an Outlook TNEF blob, by its media type or
+// the filename winmail.dat.
+func isTNEF(part *Part) bool {
+	// application/vnd.ms-tnef を使うメーラーもある
+	if part.MediaType == MEDIATYPE_TNEF || part.MediaType == "application/vnd.ms-tnef" {
+		return true
+	}
+	return strings.EqualFold(partFilename(part.Header), "winmail.dat")
+}
+
+// HasTNEF reports whether the message has an application/ms-tnef (winmail.dat) part.
+func (msg Jmessage) HasTNEF() bool {
+	err := msg.WalkParts(func(part *Part) error {
+		if isTNEF(part) {
+			return errTNEFFound
+		}
+		return nil
+	})
+	return err == errTNEFFound
+}
+
+// TNEFData returns the first application/ms-tnef (winmail.dat) part with its
+// Content-Transfer-Encoding decoded. The TNEF data itself is not parsed.
+// It returns ErrNoTNEF when there is no such part.
+func (msg Jmessage) TNEFData() ([]byte, error) {
+	var data []byte
+	err := msg.WalkParts(func(part *Part) error {
+		if !isTNEF(part) {
+			return nil
+		}
+		var err error
+		data, err = part.Bytes()
+		if err != nil {
+			return errors.Wrapf(err, "TNEFData: %s:", part.ID)
+		}
+		return errTNEFFound
+	})
+	switch {
+	case err == errTNEFFound:
+		return data, nil
+	case err != nil:
+		return nil, err
+	}
+	return nil, ErrNoTNEF
+}
@@ -0,0 +1,39 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTNEFData(t *testing.T) {
+	tnef := "\x78\x9f\x3e\x22\x00\x00\x01\x06\x90\x08\x00"
+	tests := []struct {
+		part string
+		has  bool
+	}{
+		{"Content-Type: application/ms-tnef; name=\"winmail.dat\"\r\nContent-Transfer-Encoding: base64\r\n\r\neJ8+IgAAAQaQCAA=\r\n", true},
+		{"Content-Type: Application/MS-TNEF\r\nContent-Transfer-Encoding: base64\r\n\r\neJ8+IgAAAQaQCAA=\r\n", true},
+		{"Content-Type: application/octet-stream; name=\"WINMAIL.DAT\"\r\nContent-Transfer-Encoding: base64\r\n\r\neJ8+IgAAAQaQCAA=\r\n", true},
+		{"Content-Type: application/octet-stream; name=\"data.bin\"\r\nContent-Transfer-Encoding: base64\r\n\r\neJ8+IgAAAQaQCAA=\r\n", false},
+	}
+	for _, tt := range tests {
+		eml := "Content-Type: multipart/mixed; boundary=\"BOUNDARY\"\r\n\r\n" +
+			"--BOUNDARY\r\nContent-Type: text/plain\r\n\r\nMessage body\r\n" +
+			"--BOUNDARY\r\n" + tt.part +
+			"--BOUNDARY--\r\n"
+		msg, err := ReadMessage(strings.NewReader(eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if has := msg.HasTNEF(); has != tt.has {
+			t.Errorf("test: HasTNEF error: %q (%v)", tt.part, has)
+		}
+		data, err := msg.TNEFData()
+		if tt.has && (err != nil || string(data) != tnef) {
+			t.Errorf("test: TNEFData error: %q (%q, %v)", tt.part, data, err)
+		}
+		if !tt.has && err != ErrNoTNEF {
+			t.Errorf("test: TNEFData should fail: %q (%v)", tt.part, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package jmail
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RequiredHeaders are the headers that Validate requires in a message.
+var RequiredHeaders = []string{"From", "Date"}
+
+// A MissingHeaderError is returned by Validate for a required header that is
+// absent or empty.
+type MissingHeaderError struct {
+	Key string
+}
+
+func (e *MissingHeaderError) Error() string {
+	return "jmail: missing header: " + e.Key
+}
+
+// Validate checks the structure of the message and returns all the problems
+// found, or nil: missing RequiredHeaders, a Content-Type that doesn't parse, a
+// multipart boundary missing from the body, part headers that don't parse, and
+// base64 or quoted-printable parts that don't decode. A broken multipart stops
+// the check of the rest of its parts, the other checks are still done.
+func (msg Jmessage) Validate() []error {
+	var errs []error
+	for _, key := range RequiredHeaders {
+		if strings.TrimSpace(msg.Header.Get(key)) == "" {
+			errs = append(errs, &MissingHeaderError{Key: key})
+		}
+	}
+	err := walkParts(msg.Header, msg.body(), 0, "", func(part *Part) error {
+		// デコードできない部分があっても残りのパートを続けて調べる
+		if _, err := io.Copy(io.Discard, part.Reader()); err != nil {
+			errs = append(errs, errors.Wrapf(err, "Validate: %s:", part.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, errors.Wrapf(err, "Validate:"))
+	}
+	return errs
+}
@@ -0,0 +1,57 @@
+package jmail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	msg := openTestMessage(t, "./testbody/05test-multipart.eml")
+	if errs := msg.Validate(); len(errs) != 0 {
+		t.Errorf("test: Validate error: %v", errs)
+	}
+
+	head := "From: Gopher <from@example.com>\r\nDate: Wed, 16 Sep 2015 05:32:04 +0900\r\n"
+	tests := []struct {
+		eml  string
+		errs int
+	}{
+		{head + "\r\nMessage body\r\n", 0},
+		{"Subject: test\r\n\r\nMessage body\r\n", 2},
+		{head + "Content-Type: text/plain; charset=\"utf-8\r\n\r\nMessage body\r\n", 1},
+		{head + "Content-Type: multipart/mixed\r\n\r\nMessage body\r\n", 1},
+		{head + "Content-Type: multipart/mixed; boundary=BOUNDARY\r\n\r\nMessage body\r\n", 1},
+		{head + "Content-Type: multipart/mixed; boundary=BOUNDARY\r\n\r\n" +
+			"--BOUNDARY\r\nContent-Transfer-Encoding: base64\r\n\r\n!!TWVzc2FnZSBib2R5\r\n" +
+			"--BOUNDARY\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nMessage \x1bbody\r\n" +
+			"--BOUNDARY--\r\n", 2},
+		{"Content-Type: multipart/mixed; boundary=BOUNDARY\r\n\r\n" +
+			"--BOUNDARY\r\nContent-Type text/plain\r\n\r\nMessage body\r\n" +
+			"--BOUNDARY--\r\n", 3},
+	}
+	for _, tt := range tests {
+		msg, err := ReadMessage(strings.NewReader(tt.eml))
+		if err != nil {
+			t.Fatalf("test: ReadMessage error: %v", err)
+		}
+		if errs := msg.Validate(); len(errs) != tt.errs {
+			t.Errorf("test: Validate count error: %q (%v)", tt.eml, errs)
+		}
+	}
+
+	msg, err := ReadMessage(strings.NewReader("From: Gopher <from@example.com>\r\n\r\nMessage body\r\n"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	errs := msg.Validate()
+	var missing *MissingHeaderError
+	if len(errs) != 1 || !errors.As(errs[0], &missing) || missing.Key != "Date" {
+		t.Errorf("test: Validate missing header error: %v", errs)
+	}
+	// Validate のあとも本文を読める
+	if body, err := msg.DecBody(); err != nil || string(body) != "Message body\r\n" {
+		t.Errorf("test: DecBody after Validate error: %q (%v)", body, err)
+	}
+}
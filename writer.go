@@ -0,0 +1,179 @@
+package jmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WriteUTF8 writes the message transcoded to UTF-8: the Subject is decoded and
+// re-encoded as UTF-8, and every text part is converted to UTF-8 with its
+// Content-Type charset rewritten to utf-8, its Content-Encoding decompressed
+// and its format=flowed lines joined. A text part in a charset with no decoder
+// keeps its charset and bytes.
+// Other headers and parts, and the multipart structure, are kept.
+func (msg Jmessage) WriteUTF8(w io.Writer) error {
+	header := make(textproto.MIMEHeader, len(msg.Header))
+	for key, values := range msg.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	if _, ok := header["Subject"]; ok {
+		header.Set("Subject", mime.BEncoding.Encode(CHARSET_UTF8, msg.DecSubject()))
+	}
+	keys := msg.HeaderKeys()
+
+	header, content, err := utf8Part(header, msg.body(), msg.fallbackCharset(), 0)
+	if err != nil {
+		return errors.Wrapf(err, "WriteUTF8:")
+	}
+	var buf bytes.Buffer
+	for _, key := range appendMissingKeys(keys, header) {
+		for _, value := range header[key] {
+			buf.WriteString(key + ": " + value + "\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+	if _, err := buf.WriteTo(w); err != nil {
+		return errors.Wrapf(err, "WriteUTF8:")
+	}
+	_, err = w.Write(content)
+	return errors.Wrapf(err, "WriteUTF8:")
+}
+
+// utf8Part returns the header and the content of a part transcoded to UTF-8.
+// fallback is the charset of the text parts without a charset parameter.
+func utf8Part(header textproto.MIMEHeader, body io.Reader, fallback string, depth int) (textproto.MIMEHeader, []byte, error) {
+	mediatype, params, err := parseContentType(mail.Header(header))
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case strings.HasPrefix(mediatype, MEDIATYPE_TEXT):
+		text, charset, err := readText(header, body, fallback)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := charsetDecoder(charset); err == nil {
+			// デコーダのない charset は元のバイト列のまま
+			params["charset"] = CHARSET_UTF8
+		}
+		// format=flowed の行は readText でつなげ済み
+		delete(params, "format")
+		delete(params, "delsp")
+		header.Set("Content-Type", mime.FormatMediaType(mediatype, params))
+		// Content-Encoding (gzip など) は展開済み
+		header.Del("Content-Encoding")
+		encoding := strings.ToLower(header.Get("Content-Transfer-Encoding"))
+		if encoding != ENC_BASE64 && encoding != ENC_QUOTED_PRINTABLE {
+			encoding = "8bit"
+			if hasLongLine(text) {
+				// 8bit では 998 バイトを超える行を送れない
+				encoding = ENC_QUOTED_PRINTABLE
+			}
+		}
+		header.Set("Content-Transfer-Encoding", encoding)
+		content, err := encodeTransfer(encoding, text)
+		return header, content, err
+
+	case strings.HasPrefix(mediatype, MEDIATYPE_MULTI):
+		if depth >= MaxDepth {
+			return nil, nil, ErrTooDeep
+		}
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		if err := mw.SetBoundary(params["boundary"]); err != nil {
+			params["boundary"] = mw.Boundary()
+			header.Set("Content-Type", mime.FormatMediaType(mediatype, params))
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "NextRawPart:")
+			}
+			partHeader, content, err := utf8Part(p.Header, p, fallback, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			pw, err := mw.CreatePart(partHeader)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, err := pw.Write(content); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, nil, err
+		}
+		return header, buf.Bytes(), nil
+	}
+
+	// テキスト以外はそのまま
+	content, err := readAllLimited(body, MaxBodySize)
+	return header, content, err
+}
+
+// hasLongLine reports whether text has a line longer than the 998 bytes allowed
+// by RFC 5322.
+func hasLongLine(text []byte) bool {
+	for _, line := range bytes.Split(text, []byte("\n")) {
+		if len(bytes.TrimSuffix(line, []byte("\r"))) > 998 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeTransfer encodes data with the Content-Transfer-Encoding.
+func encodeTransfer(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch encoding {
+	case ENC_BASE64:
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded + "\r\n")
+	case ENC_QUOTED_PRINTABLE:
+		qw := quotedprintable.NewWriter(&buf)
+		if _, err := qw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// appendMissingKeys appends the keys of header not in keys, in sorted order.
+func appendMissingKeys(keys []string, header textproto.MIMEHeader) []string {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+	var rest []string
+	for key := range header {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
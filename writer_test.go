@@ -0,0 +1,149 @@
+package jmail
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestWriteUTF8(t *testing.T) {
+	for _, eml := range []string{"./testbody/01test-iso2022jp.eml", "./testbody/00test.eml", "./testbody/05test-multipart.eml", "./testbody/06test-html.eml", "./testbody/07test-sjis-base64.eml"} {
+		var buf bytes.Buffer
+		if err := openTestMessage(t, eml).WriteUTF8(&buf); err != nil {
+			t.Errorf("test: WriteUTF8 error: %s (%v)", eml, err)
+			continue
+		}
+		msg, err := ParseMessage(buf.Bytes())
+		if err != nil {
+			t.Errorf("test: ParseMessage error: %s (%v)", eml, err)
+			continue
+		}
+		orig := openTestMessage(t, eml)
+		if msg.DecSubject() != orig.DecSubject() {
+			t.Errorf("test: WriteUTF8 subject error: %s (%s)", eml, msg.DecSubject())
+		}
+		want, _ := openTestMessage(t, eml).DecBody()
+		body, err := msg.DecBody()
+		if err != nil || !bytes.Equal(body, want) {
+			t.Errorf("test: WriteUTF8 body error: %s (%s, %v)", eml, body, err)
+		}
+		wantAtts, _ := openTestMessage(t, eml).Attachments()
+		msg, _ = ParseMessage(buf.Bytes())
+		atts, err := msg.Attachments()
+		if err != nil || len(atts) != len(wantAtts) {
+			t.Errorf("test: WriteUTF8 attachments error: %s (%d, %v)", eml, len(atts), err)
+			continue
+		}
+		for i := range atts {
+			if atts[i].Filename != wantAtts[i].Filename || !bytes.Equal(atts[i].Data, wantAtts[i].Data) {
+				t.Errorf("test: WriteUTF8 attachment error: %s (%s)", eml, atts[i].Filename)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := openTestMessage(t, "./testbody/01test-iso2022jp.eml").WriteUTF8(&buf); err != nil {
+		t.Fatalf("test: WriteUTF8 error: %v", err)
+	}
+	msg, _ := ParseMessage(buf.Bytes())
+	if _, params, _ := msg.ContentType(); params["charset"] != "utf-8" {
+		t.Errorf("test: WriteUTF8 charset error: (%v)", params)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("サイトを更新した")) {
+		t.Errorf("test: WriteUTF8 should write a UTF-8 body: (%s)", buf.Bytes())
+	}
+}
+
+func TestWriteUTF8Gzip(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("go go gopher!"))
+	zw.Close()
+	eml := "Subject: test\r\nContent-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Encoding: gzip\r\nContent-Transfer-Encoding: binary\r\n\r\n" + gz.String()
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := msg.WriteUTF8(&buf); err != nil {
+		t.Fatalf("test: WriteUTF8 error: %v", err)
+	}
+	msg, err = ParseMessage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("test: ParseMessage error: %v", err)
+	}
+	if msg.Header.Get("Content-Encoding") != "" {
+		t.Errorf("test: WriteUTF8 should remove Content-Encoding: (%s)", buf.Bytes())
+	}
+	if body, err := msg.DecBody(); err != nil || string(body) != "go go gopher!" {
+		t.Errorf("test: WriteUTF8 gzip body error: %q (%v)", body, err)
+	}
+}
+
+func TestWriteUTF8Flowed(t *testing.T) {
+	line := strings.Repeat("gopher ", 100)
+	eml := "Subject: test\r\nContent-Type: text/plain; charset=utf-8; format=flowed; delsp=yes\r\n\r\n" +
+		strings.Repeat(line+"\r\n", 3) + "end\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	want, _ := msg.Clone().DecBody()
+	var buf bytes.Buffer
+	if err := msg.WriteUTF8(&buf); err != nil {
+		t.Fatalf("test: WriteUTF8 error: %v", err)
+	}
+	msg, err = ParseMessage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("test: ParseMessage error: %v", err)
+	}
+	if _, params, _ := msg.ContentType(); params["format"] != "" || params["delsp"] != "" {
+		t.Errorf("test: WriteUTF8 should remove format=flowed: (%v)", params)
+	}
+	if hasLongLine(buf.Bytes()) {
+		t.Errorf("test: WriteUTF8 line length error: (%s)", msg.Header.Get("Content-Transfer-Encoding"))
+	}
+	if body, err := msg.DecBody(); err != nil || !bytes.Equal(body, want) {
+		t.Errorf("test: WriteUTF8 flowed body error: %q (%v)", body, err)
+	}
+}
+
+func TestWriteUTF8UnknownCharset(t *testing.T) {
+	eml := "Subject: test\r\nContent-Type: text/plain; charset=x-unknown\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n//5nAG8A\r\n"
+	msg, err := ReadMessage(strings.NewReader(eml))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := msg.WriteUTF8(&buf); err != nil {
+		t.Fatalf("test: WriteUTF8 error: %v", err)
+	}
+	msg, err = ParseMessage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("test: ParseMessage error: %v", err)
+	}
+	if _, params, _ := msg.ContentType(); params["charset"] != "x-unknown" {
+		t.Errorf("test: WriteUTF8 should keep an unknown charset: (%v)", params)
+	}
+	if body, _ := msg.DecBody(); string(body) != "\xff\xfeg\x00o\x00" {
+		t.Errorf("test: WriteUTF8 unknown charset body error: %q", body)
+	}
+}
+
+func TestWriteUTF8DefaultCharset(t *testing.T) {
+	msg, err := ReadMessage(strings.NewReader("Subject: test\r\nContent-Type: text/plain\r\n\r\n\x83\x65\x83\x58\x83\x67"))
+	if err != nil {
+		t.Fatalf("test: ReadMessage error: %v", err)
+	}
+	msg.SetDefaultCharset("shift_jis")
+	var buf bytes.Buffer
+	if err := msg.WriteUTF8(&buf); err != nil {
+		t.Fatalf("test: WriteUTF8 error: %v", err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\r\n\r\nテスト")) {
+		t.Errorf("test: WriteUTF8 SetDefaultCharset error: (%q)", buf.Bytes())
+	}
+}